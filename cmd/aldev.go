@@ -8,9 +8,6 @@ import (
 	"time"
 
 	"github.com/aldesgroup/aldev/utils"
-	core "github.com/aldesgroup/corego"
-	"github.com/fsnotify/fsnotify"
-	"github.com/patrickmn/go-cache"
 	"github.com/spf13/cobra"
 )
 
@@ -23,6 +20,7 @@ import (
 func Execute() {
 	err := aldevCmd.Execute()
 	if err != nil {
+		// only the usage errors get here, before any command has started something to wait for
 		os.Exit(1)
 	}
 }
@@ -50,7 +48,11 @@ var (
 	disableConfgen bool
 	disableI18nDL  bool
 	regen          bool
+	dryRun         bool
+	plainOutput    bool
 	noServe        bool
+	poll           bool
+	pollInterval   time.Duration
 )
 
 func init() {
@@ -60,6 +62,14 @@ func init() {
 	aldevCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "activates debug logging")
 	// aldevCmd.PersistentFlags().BoolVarP(&onlyAPI, "api-only", "a", false, "builds & runs only the API part, if present")
 	aldevCmd.PersistentFlags().BoolVarP(&regen, "regen", "r", false, "forces the regeneration of code and config files")
+	aldevCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false,
+		"prints the commands that would be run, and the files that would be written or removed - with diffs - without doing it")
+	aldevCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false,
+		"writes the output of the commands as is, without prefixing the lines with the commands' names in colors - e.g. for the CI")
+	aldevCmd.PersistentFlags().BoolVar(&poll, "poll", false,
+		"polls for the file changes, rather than being notified - e.g. on network filesystems, or folders mounted into a VM or a container, "+
+			"which is automatically detected on Linux")
+	aldevCmd.PersistentFlags().DurationVar(&pollInterval, "poll-interval", time.Second, "how often to poll for the file changes, when polling")
 
 	// arguments for the "aldev" command only
 	aldevCmd.Flags().BoolVarP(&swapCode, "swap", "s", false,
@@ -78,13 +88,43 @@ func GetAldevCmd() *cobra.Command {
 	return aldevCmd
 }
 
+// returns the path to the aldev config file, as given with the command arguments
+func GetConfigFileName() string {
+	return cfgFileName
+}
+
+// returns true if the verbose mode has been required with the command arguments
+func IsVerbose() bool {
+	return verbose
+}
+
+// returns true if the dry-run mode has been required with the command arguments
+func IsDryRun() bool {
+	return dryRun
+}
+
 // Function that processes the common arguments to all the aldev command & subcommands
 // and reads the content of the YAML aldev config file into a variable
 func ReadCommonArgsAndConfig() {
 	utils.SetVerbose(verbose)
+	utils.SetDryRun(dryRun)
+	utils.SetPlainOutput(plainOutput)
+	utils.SetWatchPolling(poll, pollInterval)
 	utils.SetRegen(regen)
 	utils.SetCacheDir(cacheDir)
-	utils.ReadConfig(cfgFileName)
+	ExitIfErr(nil, utils.ReadConfig(cfgFileName))
+}
+
+// ExitIfErr logs the given error, if any, and exits - after cancelling the given context, if any, and waiting for its
+// workers to be done; meant to be used by the commands, i.e. at the CLI boundary
+func ExitIfErr(ctx utils.CancelableContext, err error) {
+	if err != nil {
+		utils.Error("%v", err)
+		if ctx != nil {
+			ctx.CancelAll()
+		}
+		os.Exit(1)
+	}
 }
 
 // ----------------------------------------------------------------------------
@@ -109,56 +149,32 @@ func aldevRun(command *cobra.Command, args []string) {
 
 	// one time thing: using Aldev swap when locally developping the dependencies alongside
 	if swapCode {
-		go utils.Run("Allowing HMR to work even with dependencies", aldevCtx, true, "aldev codeswap")
+		codeswapCmd := []string{"aldev", "codeswap"}
+		if utils.IsWatchPolling() {
+			codeswapCmd = append(codeswapCmd, "--poll", "--poll-interval", utils.GetWatchPollInterval().String())
+		}
+		go utils.RunArgs("Allowing HMR to work even with dependencies", aldevCtx, true, codeswapCmd...)
 	}
 
 	// --- main loop stuff
 
 	// for which file changes are we going to restart the main loop?
-	watched := []string{cfgFileName} // Aldev's config
+	watched := utils.GetConfigFilesToWatch() // Aldev's config, and the files it depends on - or could
 
-	// adding a watcher to detect some file changes
-	watcher := utils.WatcherFor(watched...)
-	defer watcher.Close()
+	// restarting the main loop whenever these files change
+	configWatcher = utils.NewWatcher("watching Aldev's config files", func(events []*utils.WatchEvent) {
+		utils.Step("/!\\ Files changed: %s", utils.DescribeWatchEvents(events))
 
-	// loop to react to these file changes
-	go func() {
-		// capturing ghost changes on files
-		cache := cache.New(3*time.Second, time.Minute)
+		// cancelling the current loop context - waiting for the previous execution to stop gracefully - and restarting it
+		aldevCtx.RestartLoop()
 
-		// listening for events
-		for {
-			select {
-			case event := <-watcher.Events:
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					if _, alreadyCaptured := cache.Get(event.String()); !alreadyCaptured {
-						utils.Step("/!\\ File modified: %s (event = %s)", event.Name, event.String())
+		// Restarting the main building / local deployment function
+		utils.Step("Restarting the main function")
 
-						// caching to prevent stuttering
-						cache.SetDefault(event.String(), true)
-
-						// cancelling the current loop context and restarting it
-						aldevCtx.RestartLoop()
-
-						// Waiting a bit for the previous execution to stop gracefully
-						time.Sleep(1000 * time.Millisecond) // todo wait for the clean up to be finished
-
-						// Restarting the main building / local deployment function
-						utils.Step("Restarting the main function")
-
-						// go rebuilding & deploying the app again
-						go asyncPrepareAndRun(aldevCtx.GetLoopCtx())
-
-						// Waiting a bit for the new execution to start sufficiently
-						time.Sleep(1000 * time.Millisecond)
-					}
-				}
-
-			case errWatcher := <-watcher.Errors:
-				core.PanicIfErr(errWatcher)
-			}
-		}
-	}()
+		// go rebuilding & deploying the app again
+		go asyncPrepareAndRun(aldevCtx.GetLoopCtx())
+	}, watched...)
+	configWatcher.Start(aldevCtx)
 
 	// building & deploying the app
 	go asyncPrepareAndRun(aldevCtx.GetLoopCtx())
@@ -166,27 +182,46 @@ func aldevRun(command *cobra.Command, args []string) {
 	// not quitting while the context is still going
 	<-aldevCtx.Done()
 
-	time.Sleep(10 * time.Millisecond)
+	// and then, not before all the workers are done
+	aldevCtx.Wait()
 }
 
 // ----------------------------------------------------------------------------
 // Building & deploying the app once the API & Aldev's configs are settled
 // ----------------------------------------------------------------------------
 
+// the watcher of the files the config depends on
+var configWatcher *utils.Watcher
+
 // building & deploying the app
 func asyncPrepareAndRun(ctx utils.CancelableContext) {
 	// making sure we recover any big crashing error
 	defer utils.Recover(ctx, "building & deploying the app")
 
-	// reading the Aldev config again, in case it has changed
-	utils.ReadConfig(cfgFileName)
+	// the context has to wait for all this - including the cleaning - to be done, when cancelled
+	defer ctx.AddWorker("building & deploying the app")()
+
+	// reading the Aldev config again, in case it has changed; if it's now invalid, we wait for the next change
+	if errRead := utils.ReadConfig(cfgFileName); errRead != nil {
+		utils.Error("%v", errRead)
+		return
+	}
+
+	// the config may now depend on new files - e.g. newly included ones
+	configWatcher.WatchFiles(utils.GetConfigFilesToWatch()...)
 
 	// proceed to download the needed external resources
-	utils.DownloadExternalResources(ctx, !disableI18nDL)
+	if errDL := utils.DownloadExternalResources(ctx, !disableI18nDL); errDL != nil {
+		utils.Error("%v", errDL)
+		return
+	}
 
 	// Generating config files for deploying the app locally, CI / CD, etc.
 	if utils.IsDevAPI() && !disableConfgen {
-		utils.GenerateDeployFiles(ctx)
+		if errGen := utils.GenerateDeployFiles(ctx); errGen != nil {
+			utils.Error("%v", errGen)
+			return
+		}
 	}
 
 	if utils.IsDevNative() {
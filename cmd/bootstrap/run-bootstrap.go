@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path"
@@ -73,6 +74,7 @@ func init() {
 func aldevBootstrapRun(command *cobra.Command, args []string) {
 	// handling the base execution parameters
 	utils.SetVerbose(verbose)
+	utils.SetDryRun(cmd.IsDryRun())
 	utils.SetCacheDir(core.IfThenElse(utils.GetCacheDir() != "", utils.GetCacheDir(), "../tmp"))
 
 	// checking the Project's name, and that it does not exist yet
@@ -104,28 +106,35 @@ func aldevBootstrapRun(command *cobra.Command, args []string) {
 	start := time.Now()
 
 	// checking the environment
-	core.EnsureDir(utils.GetCacheDir())
-	core.EnsureNoDir(utils.GetCacheDir(), projectNameKebab)
+	utils.EnsureDir(utils.GetCacheDir())
+	utils.EnsureNoDir(utils.GetCacheDir(), projectNameKebab)
 
 	// git-cloning into the cache the template project
 	if templatePrivate {
 		firstSlashIndex := strings.Index(templateLink, "/")
-		utils.Run("git-cloning / caching the private '"+templateLink+"' repo", ctx, false,
-			"git clone git@%s:%s.git %s", templateLink[:firstSlashIndex], templateLink[firstSlashIndex+1:], projectNameKebab)
+		utils.RunArgs("git-cloning / caching the private '"+templateLink+"' repo", ctx, false,
+			"git", "clone", fmt.Sprintf("git@%s:%s.git", templateLink[:firstSlashIndex], templateLink[firstSlashIndex+1:]), projectNameKebab)
 	} else {
-		utils.Run("git-cloning / caching the public '"+templateLink+"' repo", ctx, false,
-			"git clone https://%s %s", templateLink, projectNameKebab)
+		utils.RunArgs("git-cloning / caching the public '"+templateLink+"' repo", ctx, false,
+			"git", "clone", "https://"+templateLink, projectNameKebab)
 	}
 
 	// going into this project
 	cachedProjDir := path.Join(utils.GetCacheDir(), projectNameKebab)
+
+	// in dry-run mode, the template has not been cloned, so we cannot tell more about what would be done with it
+	if utils.IsDryRun() && !core.DirExists(cachedProjDir) {
+		utils.DryRun("Would then customise the template project in '%s', move it to '%s', and push it to the Git repo",
+			cachedProjDir, core.IfThenElse(destination == "", projectNameKebab, destination))
+		return
+	}
 	cachedProjCtx := ctx.WithExecDir(cachedProjDir)
 
 	// removing obvious stuff, for starters
-	utils.Run("removing the .git folder", cachedProjCtx, false, "%s", core.RemoveCmd()+" .git")
-	utils.Run("removing the deploy folder", cachedProjCtx, false, "%s deploy", core.RemoveCmd())
-	utils.Run("removing the README file", cachedProjCtx, false, "%s README.md", core.RemoveCmd())
-	utils.Run("removing the .gitignore file", cachedProjCtx, false, "%s .gitignore", core.RemoveCmd())
+	utils.RunArgs("removing the .git folder", cachedProjCtx, false, removeCmd(".git")...)
+	utils.RunArgs("removing the deploy folder", cachedProjCtx, false, removeCmd("deploy")...)
+	utils.RunArgs("removing the README file", cachedProjCtx, false, removeCmd("README.md")...)
+	utils.RunArgs("removing the .gitignore file", cachedProjCtx, false, removeCmd(".gitignore")...)
 
 	// simple reading of the config - for real config loading, use utils.ReadConfig
 	configFilePath := path.Join(cachedProjDir, ".aldev.yaml")
@@ -137,14 +146,14 @@ func aldevBootstrapRun(command *cobra.Command, args []string) {
 	// TODO + git target repo instead of just project name, to handle auto git init & push
 
 	// --- in the project's root
-	core.ReplaceInFile(path.Join(cachedProjDir, ".aldev.yaml"), map[string]string{conf.AppName: projectNamePascal})
+	utils.ReplaceInFile(path.Join(cachedProjDir, ".aldev.yaml"), map[string]string{conf.AppName: projectNamePascal})
 
 	// --- in the API
 	goModuleName := projectNameKebab
 	templateModuleContent := core.ReadFile(path.Join(cachedProjDir, conf.API.Build.SrcDir, "go.mod"), true)
 	templateModuleName := core.After(core.Before(string(templateModuleContent), "\n"), "module ")
-	core.ReplaceInFile(path.Join(cachedProjDir, conf.API.Build.SrcDir, "go.mod"), map[string]string{templateModuleName: goModuleName})
-	core.ReplaceInFolder(path.Join(cachedProjDir, conf.API.Build.SrcDir), ".go", map[string]string{templateModuleName: goModuleName})
+	utils.ReplaceInFile(path.Join(cachedProjDir, conf.API.Build.SrcDir, "go.mod"), map[string]string{templateModuleName: goModuleName})
+	utils.ReplaceInFolder(path.Join(cachedProjDir, conf.API.Build.SrcDir), ".go", map[string]string{templateModuleName: goModuleName})
 
 	// core.ReplaceInFile(path.Join(cachedProjDir, ".aldev.yaml"), map[string]string{"apionly: false": "apionly: true"})
 	// core.ReplaceInFile(path.Join(cachedProjDir, "api", "go.mod"), map[string]string{"/libs/devotion--template": "/web/" + projectNamePascal})
@@ -166,18 +175,19 @@ func aldevBootstrapRun(command *cobra.Command, args []string) {
 
 	// trimming the clone as much as necessary, before moving it
 	if noAPI {
-		utils.Run("removing the API part", cachedProjCtx, false, "%s api", core.RemoveCmd())
+		utils.RunArgs("removing the API part", cachedProjCtx, false, removeCmd("api")...)
 	}
 	if noWeb {
-		utils.Run("removing the Web part", cachedProjCtx, false, "%s web", core.RemoveCmd())
+		utils.RunArgs("removing the Web part", cachedProjCtx, false, removeCmd("web")...)
 	}
 	if !native {
-		utils.Run("removing the native part", cachedProjCtx, false, "%s native", core.RemoveCmd())
+		utils.RunArgs("removing the native part", cachedProjCtx, false, removeCmd("native")...)
 	}
 
 	// moving it
 	projectDir := core.IfThenElse(destination == "", projectNameKebab, destination)
-	utils.Run("moving the project", ctx.WithExecDir("."), false, "rsync -a --remove-source-files %s/ %s/", path.Join(utils.GetCacheDir(), projectNameKebab), projectDir)
+	utils.RunArgs("moving the project", ctx.WithExecDir("."), false,
+		"rsync", "-a", "--remove-source-files", path.Join(utils.GetCacheDir(), projectNameKebab)+"/", projectDir+"/")
 
 	// // tweaking the config
 	// if !noAPI {
@@ -209,16 +219,21 @@ func aldevBootstrapRun(command *cobra.Command, args []string) {
 	utils.Info("Done initialising an aldev project in %s", time.Since(start))
 }
 
+// the platform's command removing the given file or folder
+func removeCmd(target string) []string {
+	return append(strings.Fields(core.RemoveCmd()), target)
+}
+
 func createReadme(ctx utils.CancelableContext, projectDir string) {
-	core.WriteStringToFile(path.Join(projectDir, "README.md"), "# Project: %s", projectNamePascal)
+	utils.WriteStringToFile(path.Join(projectDir, "README.md"), "# Project: %s", projectNamePascal)
 }
 
 func pushToGit(ctx utils.CancelableContext) {
-	utils.Run("git init (1/5)", ctx, verbose, "git init --initial-branch=main")
-	utils.Run("git init (2/5)", ctx, verbose, "git remote add origin git@%s:%s/%s.git", repo, group, projectNameKebab)
-	utils.Run("git init (3/5)", ctx, verbose, "git add .")
-	utils.Run("git init (4/5)", ctx, verbose, "git commit -m Init")
-	utils.Run("git init (5/5)", ctx, verbose, "git push --set-upstream origin main")
+	utils.RunArgs("git init (1/5)", ctx, verbose, "git", "init", "--initial-branch=main")
+	utils.RunArgs("git init (2/5)", ctx, verbose, "git", "remote", "add", "origin", fmt.Sprintf("git@%s:%s/%s.git", repo, group, projectNameKebab))
+	utils.RunArgs("git init (3/5)", ctx, verbose, "git", "add", ".")
+	utils.RunArgs("git init (4/5)", ctx, verbose, "git", "commit", "-m", "Init")
+	utils.RunArgs("git init (5/5)", ctx, verbose, "git", "push", "--set-upstream", "origin", "main")
 }
 
 // // bootstraps the native app of the project
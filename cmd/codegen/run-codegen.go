@@ -3,6 +3,8 @@ package codegen
 import (
 	"fmt"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -54,11 +56,11 @@ func aldevCodegenRun(command *cobra.Command, args []string) {
 	codegenCtx := utils.InitAldevContext(100, nil).WithAllowFailure(true)
 
 	// making sure we're applying what's decided in the go.mod file
-	must(utils.Run("Making sure we're using the right set of dependencies", buildingCtx, true, "go mod tidy"))
+	must(utils.RunArgs("Making sure we're using the right set of dependencies", buildingCtx, true, "go", "mod", "tidy"))
 
 	// control
 	if utils.GetBinDir() == "" {
-		core.PanicMsg("Aldev config item `.api.bindir` (relative path for the temp folder)  or `.lib.bindir` (if library) is empty!")
+		core.PanicMsg("Aldev config item `.api.build.bindir` (relative path for the temp folder)  or `.lib.bindir` (if library) is empty!")
 	}
 
 	// repeated commands
@@ -68,62 +70,62 @@ func aldevCodegenRun(command *cobra.Command, args []string) {
 	}
 	binName := core.PascalToKebab(utils.Config().AppName) + "-api"
 
-	mainCompileCmd := fmt.Sprintf("go build -o %s/%s%s ./main", utils.GetBinDir(), binName, execExt)
+	mainCompileCmd := []string{"go", "build", "-o", fmt.Sprintf("%s/%s%s", utils.GetBinDir(), binName, execExt), "./main"}
 
-	mainRunCmd := fmt.Sprintf("%s/%s%s -config %s -srcdir %s -bindir %s",
-		utils.Config().ResolvedBinDir(), binName, execExt,
-		path.Join(utils.GetGoSrcDir(), "conf-local.yaml"),
-		utils.GetGoSrcDir(),
-		path.Join(utils.GetGoSrcDir(), utils.GetBinDir()),
-	)
+	mainRunCmd := []string{fmt.Sprintf("%s/%s%s", utils.Config().ResolvedBinDir(), binName, execExt),
+		"-config", path.Join(utils.GetGoSrcDir(), "conf-local.yaml"),
+		"-srcdir", utils.GetGoSrcDir(),
+		"-bindir", path.Join(utils.GetGoSrcDir(), utils.GetBinDir()),
+	}
 
 	if utils.Config().API != nil {
-		mainRunCmd = fmt.Sprintf("%s -docpath %s", mainRunCmd, utils.Config().API.Doc.Path)
-		mainRunCmd = fmt.Sprintf("%s -othersrcdirs %s", mainRunCmd, strings.Join(utils.Config().API.LocalDev.WatchAlso, ","))
+		mainRunCmd = append(mainRunCmd, "-docpath", utils.Config().API.Doc.Path)
+		mainRunCmd = append(mainRunCmd, "-othersrcdirs", strings.Join(utils.Config().API.LocalDev.WatchAlso, ","))
 	}
 	if utils.Config().Web != nil {
-		mainRunCmd = fmt.Sprintf("%s -webdir %s", mainRunCmd, utils.Config().Web.SrcDir)
+		mainRunCmd = append(mainRunCmd, "-webdir", utils.Config().Web.SrcDir)
 	}
 	if utils.Config().Native != nil {
-		mainRunCmd = fmt.Sprintf("%s -nativedir %s", mainRunCmd, utils.Config().Native.SrcDir)
+		mainRunCmd = append(mainRunCmd, "-nativedir", utils.Config().Native.SrcDir)
 	}
 
 	// compilation n°1 - this is needed to have the run command up-to-date
-	must(utils.Run("Compiling & formatting the code", buildingCtx, true, "%s", mainCompileCmd))
+	must(utils.RunArgs("Compiling & formatting the code", buildingCtx, true, mainCompileCmd...))
 
 	if compilationOnly {
 		return
 	}
 
-	regenArg := ""
+	regenArg := []string{}
 	if utils.IsRegen() {
-		regenArg = " -regen"
+		regenArg = []string{"-regen"}
 	}
 
 	// generation step n°1
-	must(utils.Run("Generating stuff: DB list, BOclasses, BO registry...", codegenCtx, true, "%s", mainRunCmd+" -codegen 1"+regenArg))
+	must(utils.RunArgs("Generating stuff: DB list, BOclasses, BO registry...", codegenCtx, true, codegenStepCmd(mainRunCmd, 1, regenArg...)...))
 
 	// compilation n°2
 	if codeHasChanged() {
-		must(utils.Run("Does it still compile after codegen step 1?", buildingCtx, true, "%s", mainCompileCmd))
+		must(utils.RunArgs("Does it still compile after codegen step 1?", buildingCtx, true, mainCompileCmd...))
 	}
 
 	// generation step n°2
-	must(utils.Run("Generating stuff: BO models...", codegenCtx, true, "%s", mainRunCmd+" -codegen 2"+regenArg))
+	must(utils.RunArgs("Generating stuff: BO models...", codegenCtx, true, codegenStepCmd(mainRunCmd, 2, regenArg...)...))
 
 	// compilation n°3
 	if codeHasChanged() {
-		must(utils.Run("Does it still compile after codegen step 2?", buildingCtx, true, "%s", mainCompileCmd))
+		must(utils.RunArgs("Does it still compile after codegen step 2?", buildingCtx, true, mainCompileCmd...))
 	}
 
 	// generation step n°3
-	serversArg := ""
+	serversArg := []string{}
 	if utils.Config().API != nil && utils.Config().Deploying != nil && utils.Config().Deploying.Platform != nil {
 		if servers := utils.GetRemoteDeploymentGenerator().GetServers(); len(servers) > 0 {
-			serversArg = fmt.Sprintf(" -servers %s", core.MapToString(servers, false, ":", "|"))
+			serversArg = []string{"-servers", core.MapToString(servers, false, ":", "|")}
 		}
 	}
-	must(utils.Run("Generating stuff: BO vmaps, BO web models, etc...", codegenCtx, true, "%s", mainRunCmd+" -codegen 3"+regenArg+serversArg))
+	must(utils.RunArgs("Generating stuff: BO vmaps, BO web models, etc...", codegenCtx, true,
+		codegenStepCmd(mainRunCmd, 3, append(regenArg, serversArg...)...)...))
 
 	// generation step n°3-bis
 	if codeHasChanged() {
@@ -132,14 +134,14 @@ func aldevCodegenRun(command *cobra.Command, args []string) {
 
 	// compilation n°4 -
 	if codeHasChanged() {
-		must(utils.Run("Does it still compile after codegen step 3?", buildingCtx, true, "%s", mainCompileCmd))
+		must(utils.RunArgs("Does it still compile after codegen step 3?", buildingCtx, true, mainCompileCmd...))
 	}
 
 	// generation step n°4 = verification
-	must(utils.Run("Checking the code...", codegenCtx, true, "%s", mainRunCmd+" -codegen 4"+regenArg))
+	must(utils.RunArgs("Checking the code...", codegenCtx, true, codegenStepCmd(mainRunCmd, 4, regenArg...)...))
 
 	// formatting
-	must(utils.Run("Formatting the code", codegenCtx, true, "gofumpt -w %s %s", path.Join(utils.GetGoSrcDir(), "_include"), path.Join(utils.GetGoSrcDir(), "main")))
+	must(utils.RunArgs("Formatting the code", codegenCtx, true, "gofumpt", "-w", path.Join(utils.GetGoSrcDir(), "_include"), path.Join(utils.GetGoSrcDir(), "main")))
 
 	// migrating the DBs if needed
 	// if !utils.IsDevLibrary() {
@@ -148,8 +150,8 @@ func aldevCodegenRun(command *cobra.Command, args []string) {
 
 	// under Windows, the executable for codegen and API serving is not the same - we need to build the executable for the containers
 	if core.IsWindows() && !noContainer && codeHasChanged() {
-		secondaryCompileCmd := fmt.Sprintf("go build -o %s/%s ./main", utils.GetBinDir(), binName)
-		must(utils.Run("Compiling for containerization (Linux)", buildingCtx.WithEnvVars("GOOS=linux"), true, "%s", secondaryCompileCmd))
+		secondaryCompileCmd := []string{"go", "build", "-o", fmt.Sprintf("%s/%s", utils.GetBinDir(), binName), "./main"}
+		must(utils.RunArgs("Compiling for containerization (Linux)", buildingCtx.WithEnvVars("GOOS=linux"), true, secondaryCompileCmd...))
 	}
 
 	// linting / checking the code quality
@@ -183,8 +185,9 @@ func completeRecentCode(newerThan time.Time, dir string) {
 			if strings.HasSuffix(entry.Name(), "--.go") {
 				if filepath := path.Join(dir, entry.Name()); utils.IsRegen() || core.EnsureModTime(filepath).After(newerThan) {
 					utils.Info("Completing code for file: %s", filepath)
-					utils.QuickRun("Adding missing tags", "gomodifytags -file %s -all -add-tags json,io:in|i*|o*,desc: -transform camelcase -w --quiet -skip-unexported", filepath)
-					utils.QuickRun("Aligning the tags", "formattag -file %s", filepath)
+					utils.QuickRunArgs("Adding missing tags", "gomodifytags", "-file", filepath, "-all", "-add-tags", "json,io:in|i*|o*,desc:",
+						"-transform", "camelcase", "-w", "--quiet", "-skip-unexported")
+					utils.QuickRunArgs("Aligning the tags", "formattag", "-file", filepath)
 
 				}
 			}
@@ -228,7 +231,7 @@ func apiDocLint() {
 			// 	core.PanicIfErr(exec.Command(browser, docReport).Start())
 			// }
 
-			utils.QuickRun("Checking the API doc quality", "vacuum lint --ignore-polymorph-circle-ref --ignore-array-circle-ref %s", docPath)
+			utils.QuickRunArgs("Checking the API doc quality", "vacuum", "lint", "--ignore-polymorph-circle-ref", "--ignore-array-circle-ref", docPath)
 			utils.Info("To know more about the errors and warnings here: \n\n"+
 				"vacuum dashboard --ignore-polymorph-circle-ref --ignore-array-circle-ref %s\n\n", docPath)
 		}
@@ -251,6 +254,11 @@ func must(result bool) {
 	}
 }
 
+// returns the command to run the given codegen step, with the given extra args
+func codegenStepCmd(mainRunCmd []string, step int, extraArgs ...string) []string {
+	return append(append(slices.Clone(mainRunCmd), "-codegen", strconv.Itoa(step)), extraArgs...)
+}
+
 func withDefault(value, defaultValue string) string {
 	return core.IfThenElse(value != "", value, defaultValue)
 }
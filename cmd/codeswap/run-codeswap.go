@@ -4,7 +4,6 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,8 +11,6 @@ import (
 	"github.com/aldesgroup/aldev/cmd"
 	"github.com/aldesgroup/aldev/utils"
 	core "github.com/aldesgroup/corego"
-	"github.com/fsnotify/fsnotify"
-	"github.com/patrickmn/go-cache"
 	"github.com/spf13/cobra"
 )
 
@@ -31,10 +28,9 @@ var aldevSwapCmd = &cobra.Command{
 }
 
 var (
-	folders        map[string]bool
-	done           map[string]bool
-	sets           []*swapSet
-	watchedFolders []string
+	done      map[string]bool
+	sets      []*swapSet
+	goCodeCtx utils.CancelableContext // the context to sync the Go.sum file in
 )
 
 func init() {
@@ -71,67 +67,35 @@ func aldevSwapRun(command *cobra.Command, args []string) {
 	aldevCtx := utils.InitAldevContext(10, setFinished)
 
 	// which files are going to be impacted?
-	sets, watchedFolders = getWatchedFilesAndFolders(aldevCtx)
+	sets = getSwapSets(aldevCtx)
+	goCodeCtx = aldevCtx.NewChildContext().WithExecDir(utils.GetGoSrcDir())
 
 	// performing the initial swaps
 	doAllTheSwaps(aldevCtx, false, true)
 
-	// adding a watcher to detect some file changes, for additional needed swaps
-	watcher := utils.WatcherFor(watchedFolders...)
+	// watching all the files here, to redo the swaps if something is changed
+	// so as to handle new files, or new imports in existing files for instance
+	watchingDone := utils.NewWatcher("watching the swapped files", func(events []*utils.WatchEvent) {
+		utils.Debug("/!\\ Files changed: %s", utils.DescribeWatchEvents(events))
+
+		// which files are going to be impacted NOW?
+		sets = getSwapSets(aldevCtx)
+
+		// performing the swaps on the newly computed sets
+		doAllTheSwaps(aldevCtx, false, false)
+	}, swapRoots()...).WithSkippedDirs(func(dirPath string) bool {
+		return isSkippedSwapDir(filepath.Base(dirPath))
+	}).WithEventFilter(isSwapRelevant).Start(aldevCtx)
 
 	// making sure we'll roll the changes back at the end
 	defer func() {
-		// let's stop the watching right away
-		core.PanicIfErr(watcher.Close())
-
-		// TODO like in Aldev, wait for this to be done instead of sleeping
-		time.Sleep(10 * time.Millisecond)
+		// waiting for the watching to be stopped
+		<-watchingDone
 
 		// performing the swaps, in reverse
 		doAllTheSwaps(aldevCtx, true, true)
 	}()
 
-	// watching all the files here and rebooting the watching if something is changed
-	// so as to handle new files, or new imports in existing files for instance
-	go func() {
-		// capturing ghost changes on files
-		cache := cache.New(3*time.Second, time.Minute)
-
-		// listening for events
-		for {
-			select {
-			case event := <-watcher.Events:
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					if _, alreadyCaptured := cache.Get(event.String()); !alreadyCaptured {
-						// let's wait a bit first
-						time.Sleep(100 * time.Millisecond)
-
-						utils.Debug("/!\\ File modified: %s (event = %s)", event.Name, event.String())
-
-						// caching to prevent stuttering
-						cache.SetDefault(event.String(), true)
-
-						// which files are going to be impacted NOW?
-						sets, watchedFolders = getWatchedFilesAndFolders(aldevCtx)
-
-						// adding a watcher to detect some file changes
-						core.PanicIfErr(watcher.Close()) // closing the old one
-						watcher = utils.WatcherFor(watchedFolders...)
-
-						// let's wait a bit here than the FS has finished doing it's stuff
-						time.Sleep(200 * time.Millisecond)
-
-						// performing the swaps on the newly computed sets
-						doAllTheSwaps(aldevCtx, false, false)
-					}
-				}
-
-			case errWatcher := <-watcher.Errors:
-				core.PanicIfErr(errWatcher)
-			}
-		}
-	}()
-
 	// not quitting while the context is still going
 	<-aldevCtx.Done()
 }
@@ -158,16 +122,49 @@ func doAllTheSwaps(ctx utils.CancelableContext, rollback bool, startOrFinish boo
 	// bit of logging
 	utils.Info("All the code swapping done in %s", time.Since(start))
 
-	// waiting a bit here in order to prevent the watcher to detect the changes done here
-	time.Sleep(50 * time.Millisecond)
-
 	// syncing the Go.sum file with the swaps done
 	if utils.Config().API != nil || utils.Config().Lib != nil {
-		goCodeCtx := utils.InitAldevContext(100, nil).WithExecDir(utils.GetGoSrcDir())
-		utils.Run("Making sure the Go.sum file is synced", goCodeCtx, false, "go mod tidy")
+		// when rolling back at the end, everything's been cancelled, but the syncing is needed all the same
+		tidyCtx := goCodeCtx
+		if tidyCtx.Err() != nil {
+			tidyCtx = utils.NewBaseContext().WithExecDir(utils.GetGoSrcDir())
+		}
+		utils.RunArgs("Making sure the Go.sum file is synced", tidyCtx, false, "go", "mod", "tidy")
+		recordSwapWrites(path.Join(utils.GetGoSrcDir(), "go.mod"), path.Join(utils.GetGoSrcDir(), "go.sum"))
+	}
+}
+
+// the files written by the swaps - with their modification time then - so as not to redo the swaps because of them
+var (
+	swapWrites   = map[string]time.Time{}
+	swapWritesMx sync.Mutex
+)
+
+func recordSwapWrites(filenames ...string) {
+	swapWritesMx.Lock()
+	defer swapWritesMx.Unlock()
+
+	for _, filename := range filenames {
+		if fileInfo, errStat := os.Stat(filename); errStat == nil {
+			swapWrites[filepath.Clean(filename)] = fileInfo.ModTime()
+		}
 	}
 }
 
+// returns true if the given change is the last write done by the swaps - and not a change made since then
+func isSwapWrite(event *utils.WatchEvent) bool {
+	swapWritesMx.Lock()
+	defer swapWritesMx.Unlock()
+
+	writeTime, written := swapWrites[filepath.Clean(event.Path)]
+	if !written || event.Op&(utils.WatchRemove|utils.WatchRename) != 0 {
+		return false
+	}
+	fileInfo, errStat := os.Stat(event.Path)
+
+	return errStat == nil && fileInfo.ModTime().Equal(writeTime)
+}
+
 // a set associates a swap config, and the files that should be modified according to it
 type swapSet struct {
 	swapConf *utils.CodeSwapsConfig
@@ -175,8 +172,7 @@ type swapSet struct {
 }
 
 // builds all the sets for all the swap configs configured
-func getWatchedFilesAndFolders(ctx utils.CancelableContext) (sets []*swapSet, watchedFolders []string) {
-	folders = map[string]bool{}
+func getSwapSets(ctx utils.CancelableContext) (sets []*swapSet) {
 	done = map[string]bool{}
 
 	for _, swapConf := range utils.Config().CodeSwaps {
@@ -184,33 +180,56 @@ func getWatchedFilesAndFolders(ctx utils.CancelableContext) (sets []*swapSet, wa
 		sets = append(sets, (&swapSet{swapConf: swapConf}).buildFrom(ctx, swapConf.From))
 	}
 
-	for folder := range folders {
-		watchedFolders = append(watchedFolders, folder)
-	}
+	return
+}
 
-	sort.Strings(watchedFolders)
+// the folders to watch, i.e. the ones the swap configs are applied from
+func swapRoots() (roots []string) {
+	for _, swapConf := range utils.Config().CodeSwaps {
+		roots = append(roots, swapConf.From)
+	}
 
 	return
 }
 
+// the folders in which no swap is done
+func isSkippedSwapDir(dirName string) bool {
+	return dirName == "node_modules" || dirName == ".git" || dirName == "dist" || dirName == "vendor"
+}
+
+// only the changes on the files targeted by a swap config - or on new folders, which may contain some - matter, unless
+// they've been made by the swaps themselves
+func isSwapRelevant(event *utils.WatchEvent) bool {
+	if isSwapWrite(event) {
+		return false
+	}
+	if event.Op&utils.WatchCreate != 0 && core.DirExists(event.Path) {
+		return true
+	}
+	for _, swapConf := range utils.Config().CodeSwaps {
+		for _, targetPath := range swapConf.For {
+			if matched, _ := filepath.Match(targetPath, filepath.Base(event.Path)); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // gathering all the files corresponding to the same swap config
 func (thisSet *swapSet) buildFrom(ctx utils.CancelableContext, dir string) *swapSet {
 	for _, entry := range core.EnsureReadDir(dir) {
 		filename := path.Join(dir, entry.Name())
 		if entry.IsDir() {
-			if entry.Name() != "node_modules" && entry.Name() != ".git" && entry.Name() != "dist" && entry.Name() != "vendor" {
-				initialNbFolders := len(folders)
+			if !isSkippedSwapDir(entry.Name()) {
 				thisSet.buildFrom(ctx, filename)
-				if len(folders) > initialNbFolders {
-					folders[dir] = true
-				}
 			}
 		} else {
 			for _, targetPath := range thisSet.swapConf.For {
 				matched, _ := filepath.Match(targetPath, entry.Name())
 				if matched && !done[filename] {
 					thisSet.files = append(thisSet.files, filename)
-					folders[dir] = true
 					done[filename] = true
 					utils.Debug("Will be watching file: %s", filename)
 				}
@@ -257,7 +276,8 @@ func (thisSet *swapSet) doSwaps(_ utils.CancelableContext, rollback bool) {
 				direction = "reverse"
 			}
 			utils.Info("File %s is being %s-swapped", filename, direction)
-			core.WriteStringToFile(filename, "%s", modifiedText)
+			utils.WriteStringToFile(filename, "%s", modifiedText)
+			recordSwapWrites(filename)
 		}
 	}
 }
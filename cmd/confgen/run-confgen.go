@@ -35,5 +35,5 @@ func aldevConfgenRun(command *cobra.Command, args []string) {
 	cmd.ReadCommonArgsAndConfig()
 
 	// Generating all the deployment files
-	utils.GenerateDeployFiles(nil)
+	cmd.ExitIfErr(nil, utils.GenerateDeployFiles(nil))
 }
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/aldesgroup/aldev/cmd"
+	"github.com/aldesgroup/aldev/utils"
+	"github.com/spf13/cobra"
+)
+
+// ----------------------------------------------------------------------------
+// Command declaration
+// ----------------------------------------------------------------------------
+
+// aldevConfigCmd represents a subcommand
+var aldevConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Helps with the Aldev config file",
+	Long: "Use with a subcommand: schema (prints the JSON Schema of the Aldev config file), " +
+		"show (prints the configuration as resolved by Aldev), diff (compares the API runtime configs of 2 environments), " +
+		"or migrate (updates the config files to the current format)",
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Prints the JSON Schema describing the Aldev config file",
+	Long: "Prints the JSON Schema describing the Aldev config file, with the descriptions of all the keys, " +
+		"to be used by an editor (e.g. with the yaml-language-server) or in CI checks",
+	Run: aldevConfigSchemaRun,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Prints the fully resolved Aldev configuration",
+	Long: "Prints the Aldev configuration as Aldev understands it, i.e. with the default & computed values, " +
+		"the detected development modes, and the API runtime config resolved for each environment",
+	Run: aldevConfigShowRun,
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff <envA> <envB>",
+	Short: "Compares the API runtime configs of 2 environments",
+	Long: "Compares the API runtime configs of 2 environments - e.g. 'staging' & 'production' - as they would be generated, " +
+		"and prints the added (+), removed (-) and changed (~) keys, with the secrets & credentials masked",
+	Args: cobra.ExactArgs(2),
+	Run:  aldevConfigDiffRun,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Rewrites the Aldev config files into the current config format",
+	Long: "Rewrites the Aldev config file - and the files it includes, and its local overlay - " +
+		"from an older config format into the current one, keeping the comments",
+	Run: aldevConfigMigrateRun,
+}
+
+var (
+	schemaOutput string
+	showEnv      string
+	showJSON     bool
+)
+
+func init() {
+	// linking to the root command
+	aldevConfigCmd.AddCommand(configSchemaCmd)
+	aldevConfigCmd.AddCommand(configShowCmd)
+	aldevConfigCmd.AddCommand(configDiffCmd)
+	aldevConfigCmd.AddCommand(configMigrateCmd)
+	cmd.GetAldevCmd().AddCommand(aldevConfigCmd)
+
+	configSchemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "the file where to write the schema, instead of the standard output")
+	configShowCmd.Flags().StringVarP(&showEnv, "env", "e", "", "only shows the API runtime config of this environment, e.g. 'local'")
+	configShowCmd.Flags().BoolVarP(&showJSON, "json", "j", false, "prints the configuration as JSON rather than YAML")
+}
+
+// ----------------------------------------------------------------------------
+// Main logic
+// ----------------------------------------------------------------------------
+
+func aldevConfigSchemaRun(command *cobra.Command, args []string) {
+	// no need for the config file here
+	schema := utils.ConfigJSONSchema()
+
+	// printing out, or writing to a file
+	if schemaOutput == "" {
+		fmt.Println(string(schema))
+	} else {
+		utils.WriteStringToFile(schemaOutput, "%s\n", string(schema))
+		utils.Info("Aldev config JSON schema written into: %s", schemaOutput)
+	}
+}
+
+func aldevConfigShowRun(command *cobra.Command, args []string) {
+	// Reading this command's arguments, and reading the aldev YAML config file
+	cmd.ReadCommonArgsAndConfig()
+
+	// printing out the resolved config
+	resolved, errResolve := utils.GetResolvedConfig(showEnv, showJSON)
+	cmd.ExitIfErr(nil, errResolve)
+	fmt.Println(string(resolved))
+}
+
+func aldevConfigDiffRun(command *cobra.Command, args []string) {
+	// Reading this command's arguments, and reading the aldev YAML config file
+	cmd.ReadCommonArgsAndConfig()
+
+	// printing out the differences
+	diff, errDiff := utils.GetAPIConfDiff(args[0], args[1])
+	cmd.ExitIfErr(nil, errDiff)
+	if len(diff) == 0 {
+		utils.Info("No difference between the API configs of '%s' and '%s'", args[0], args[1])
+		return
+	}
+	for _, line := range diff {
+		fmt.Println(line)
+	}
+}
+
+func aldevConfigMigrateRun(command *cobra.Command, args []string) {
+	// not reading the config here, since it might not be readable before being migrated
+	utils.SetVerbose(cmd.IsVerbose())
+	utils.SetDryRun(cmd.IsDryRun())
+
+	// migrating all the config files
+	cmd.ExitIfErr(nil, utils.MigrateConfigFiles(cmd.GetConfigFileName()))
+}
@@ -38,5 +38,6 @@ func aldevDoctorRun(command *cobra.Command, args []string) {
 	aldevCtx := utils.InitAldevContext(100, nil)
 
 	// downloading various external resource in parallel
-	utils.CheckDeps(aldevCtx, true)
+	_, errCheck := utils.CheckDeps(aldevCtx, true)
+	cmd.ExitIfErr(aldevCtx, errCheck)
 }
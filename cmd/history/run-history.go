@@ -0,0 +1,136 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldesgroup/aldev/cmd"
+	"github.com/aldesgroup/aldev/utils"
+	"github.com/spf13/cobra"
+)
+
+// ----------------------------------------------------------------------------
+// Command declaration
+// ----------------------------------------------------------------------------
+
+// aldevHistoryCmd represents a subcommand
+var aldevHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Lists the commands run by Aldev",
+	Long: "Lists the commands run by Aldev, as recorded in the journal kept in the cache folder, with when they were run, " +
+		"how long they took, and why they failed; use the 'replay' subcommand to run one of them again",
+	Run: aldevHistoryRun,
+}
+
+var historyReplayCmd = &cobra.Command{
+	Use:   "replay <id>",
+	Short: "Runs again a command from the history",
+	Long: "Runs again the command with the given ID - as listed by 'aldev history' - from the same directory, " +
+		"but with the current env vars, since their values are not recorded",
+	Args: cobra.ExactArgs(1),
+	Run:  aldevHistoryReplayRun,
+}
+
+var (
+	historyLast   int
+	historyFailed bool
+	historyGrep   string
+	historySince  string
+	historyJSON   bool
+)
+
+func init() {
+	// linking to the root command
+	cmd.GetAldevCmd().AddCommand(aldevHistoryCmd)
+	aldevHistoryCmd.AddCommand(historyReplayCmd)
+
+	// the filters
+	aldevHistoryCmd.Flags().IntVarP(&historyLast, "last", "l", 20, "only lists this number of the most recent commands; 0 for all of them")
+	aldevHistoryCmd.Flags().BoolVarP(&historyFailed, "failed", "x", false, "only lists the commands that failed")
+	aldevHistoryCmd.Flags().StringVarP(&historyGrep, "grep", "g", "", "only lists the commands, or their reasons, containing this text")
+	aldevHistoryCmd.Flags().StringVar(&historySince, "since", "", "only lists the commands run since this long ago, e.g. '2h'")
+	aldevHistoryCmd.Flags().BoolVarP(&historyJSON, "json", "j", false, "prints the whole entries, as JSON lines")
+}
+
+// ----------------------------------------------------------------------------
+// Main logic
+// ----------------------------------------------------------------------------
+
+func aldevHistoryRun(command *cobra.Command, args []string) {
+	// Reading this command's arguments, and reading the aldev YAML config file
+	cmd.ReadCommonArgsAndConfig()
+
+	// reading the journal
+	filter := &utils.JournalFilter{Last: historyLast, Failed: historyFailed, Grep: historyGrep}
+	if historySince != "" {
+		since, errParse := time.ParseDuration(historySince)
+		if errParse == nil && since <= 0 {
+			errParse = errors.New("it should be positive")
+		}
+		cmd.ExitIfErr(nil, wrapInputErr(errParse, "Invalid duration for --since: '%s'", historySince))
+		filter.Since = since
+	}
+	entries := utils.ReadJournal(filter)
+	if len(entries) == 0 {
+		utils.Info("No command found in the history")
+		return
+	}
+
+	// printing the entries out
+	for _, entry := range entries {
+		if historyJSON {
+			entryBytes, errMarsh := json.Marshal(entry)
+			cmd.ExitIfErr(nil, errMarsh)
+			fmt.Println(string(entryBytes))
+			continue
+		}
+
+		fmt.Println(entry.String())
+		if !entry.Succeeded() && !entry.Canceled {
+			fmt.Println("    error: " + entry.Error)
+			if entry.StderrTail != "" {
+				fmt.Println("    " + strings.ReplaceAll(entry.StderrTail, "\n", "\n    "))
+			}
+		}
+	}
+}
+
+func aldevHistoryReplayRun(command *cobra.Command, args []string) {
+	// Reading this command's arguments, and reading the aldev YAML config file
+	cmd.ReadCommonArgsAndConfig()
+
+	// the entry to replay
+	id, errParse := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if errParse == nil && id <= 0 {
+		errParse = errors.New("it should be positive")
+	}
+	cmd.ExitIfErr(nil, wrapInputErr(errParse, "Invalid history entry ID: '%s'", args[0]))
+
+	// the main cancelable context, that should stop everything
+	aldevCtx := utils.InitAldevContext(100, nil)
+
+	ok, errReplay := utils.ReplayJournalEntry(aldevCtx.NewChildContext().WithAllowFailure(true), id)
+	cmd.ExitIfErr(aldevCtx, errReplay)
+	if !ok {
+		aldevCtx.CancelAll()
+		os.Exit(1)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Utils
+// ----------------------------------------------------------------------------
+
+// describes what's wrong with the input the given error is about, if any
+func wrapInputErr(err error, msg string, params ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf(msg+": %w", append(params, err)...)
+}
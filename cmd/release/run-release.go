@@ -62,5 +62,5 @@ func handleReleaseRun(release utils.ReleaseType) {
 	aldevCtx := utils.InitAldevContext(100, nil)
 
 	// creating the release in parallel
-	utils.MakeRelease(aldevCtx, release)
+	cmd.ExitIfErr(aldevCtx, utils.MakeRelease(aldevCtx, release))
 }
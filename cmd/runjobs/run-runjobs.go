@@ -41,5 +41,5 @@ func aldevRunJobsRun(command *cobra.Command, args []string) {
 	aldevCtx := utils.InitAldevContext(100, nil)
 
 	// downloading various external resource in parallel
-	utils.RunJobs(aldevCtx, parallel)
+	cmd.ExitIfErr(aldevCtx, utils.RunJobs(aldevCtx, parallel))
 }
@@ -5,15 +5,14 @@ go 1.26.4
 require (
 	github.com/aldesgroup/corego v1.0.2
 	github.com/fsnotify/fsnotify v1.10.1
-	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/spf13/cobra v1.10.2
+	golang.org/x/sys v0.43.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	golang.org/x/sys v0.43.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
 
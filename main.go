@@ -9,7 +9,9 @@ import (
 	_ "github.com/aldesgroup/aldev/cmd/codegen"
 	_ "github.com/aldesgroup/aldev/cmd/codeswap"
 	_ "github.com/aldesgroup/aldev/cmd/confgen"
+	_ "github.com/aldesgroup/aldev/cmd/config"
 	_ "github.com/aldesgroup/aldev/cmd/doctor"
+	_ "github.com/aldesgroup/aldev/cmd/history"
 	_ "github.com/aldesgroup/aldev/cmd/refresh"
 	_ "github.com/aldesgroup/aldev/cmd/release"
 	_ "github.com/aldesgroup/aldev/cmd/runjobs"
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"sync"
 
 	core "github.com/aldesgroup/corego"
 )
@@ -31,19 +30,24 @@ type Module struct {
 	// Reuse      bool       // reuse of old module info is safe
 }
 
-// Checks the project's dependencies in all its parts
-func CheckDeps(ctx CancelableContext, printDeps bool) bool {
+// CheckDeps checks the project's dependencies in all its parts, and returns true if some of them are outdated; the
+// errors met while checking are returned, joined
+func CheckDeps(ctx CancelableContext, printDeps bool) (outdated bool, err error) {
+	defer catchPanic(&err, "checking the dependencies")
+
 	// results
 	var nativeDeps, apiDeps, webDeps string
 	var goDep bool
 	var apiOutdatedDeps []*Module
 
 	// checking the outdated deps in parallel
-	var wg sync.WaitGroup
-	wg.Go(func() { nativeDeps = checkNativeDeps() })
-	wg.Go(func() { apiDeps, apiOutdatedDeps, goDep = checkAPIDeps() })
-	wg.Go(func() { webDeps = checkWebappDeps() })
-	wg.Wait()
+	group := new(errorGroup)
+	group.Go(func() error { nativeDeps = checkNativeDeps(); return nil }, "checking the native app's deps")
+	group.Go(func() error { apiDeps, apiOutdatedDeps, goDep = checkAPIDeps(); return nil }, "checking the API's deps")
+	group.Go(func() error { webDeps = checkWebappDeps(); return nil }, "checking the web app's deps")
+	if errCheck := group.Wait(); errCheck != nil {
+		return false, errCheck
+	}
 
 	// dealing with the dependencies - API part
 	if apiDeps != "" {
@@ -103,20 +107,20 @@ func CheckDeps(ctx CancelableContext, printDeps bool) bool {
 	}
 
 	// returning, for callersgo list
-	return nativeDeps != "" || apiDeps != "" || webDeps != ""
+	return nativeDeps != "" || apiDeps != "" || webDeps != "", nil
 }
 
 // Checking the API's dependencies
 func checkAPIDeps() (string, []*Module, bool) {
 	if IsDevAPI() || IsDevLibrary() {
 		// controlling the Go version first
-		goVersion := string(RunAndGet("Checking Go version", GetGoSrcDir(), true, "%s", "go list -mod=mod -m -u go"))
+		goVersion := string(RunAndGetArgs("Checking Go version", GetGoSrcDir(), true, "go", "list", "-mod=mod", "-m", "-u", "go"))
 		if strings.HasSuffix((strings.TrimSpace(goVersion)), "]") {
 			return goVersion, nil, true
 		}
 
 		// this list all the dependencies, not only the outdated ones
-		allDepsString := string(RunAndGet("Checking the API's deps", GetGoSrcDir(), false, "%s", "go list -mod=mod -u -m -json all"))
+		allDepsString := string(RunAndGetArgs("Checking the API's deps", GetGoSrcDir(), false, "go", "list", "-mod=mod", "-u", "-m", "-json", "all"))
 		allDepsString = "[" + strings.ReplaceAll(allDepsString, "\n", "") + "]"
 		allDepsString = strings.ReplaceAll(allDepsString, "}{", "},{")
 
@@ -144,7 +148,7 @@ func checkAPIDeps() (string, []*Module, bool) {
 // Checking the web app's dependencies
 func checkWebappDeps() string {
 	if IsDevWebApp() {
-		return string(RunAndGet("Checking the web app's deps", Config().Web.SrcDir, false, "%s", "ncu --format group"))
+		return string(RunAndGetArgs("Checking the web app's deps", Config().Web.SrcDir, false, "ncu", "--format", "group"))
 	}
 
 	return ""
@@ -153,7 +157,7 @@ func checkWebappDeps() string {
 // Checking the native app's dependencies
 func checkNativeDeps() string {
 	if IsDevNative() {
-		result := string(RunAndGet("Checking the native app's deps", Config().Native.SrcDir, false, "%s", "ncu --format group"))
+		result := string(RunAndGetArgs("Checking the native app's deps", Config().Native.SrcDir, false, "ncu", "--format", "group"))
 
 		keptLib := false
 		keptLines := []string{}
@@ -6,6 +6,8 @@ package utils
 import (
 	"os"
 	"path"
+	"reflect"
+	"slices"
 	"strings"
 
 	core "github.com/aldesgroup/corego"
@@ -15,6 +17,7 @@ import (
 var (
 	// the instance bearing all the configuration
 	config         *AldevConfig
+	configFiles    []string // all the files the config has been read from
 	cacheDirectory string
 )
 
@@ -26,6 +29,29 @@ func Config() *AldevConfig {
 	return config
 }
 
+// returns all the files the current config has been read from
+func GetConfigFiles() []string {
+	return configFiles
+}
+
+// GetConfigFilesToWatch returns the files the current config has been read from, along with the optional files next
+// to the main config file - env file, local overlay, secrets - that would change it if they were created
+func GetConfigFilesToWatch() []string {
+	if len(configFiles) == 0 {
+		return nil
+	}
+
+	mainFile := configFiles[0]
+	filesToWatch := slices.Clone(configFiles)
+	for _, optionalFile := range []string{path.Join(path.Dir(mainFile), configEnvFILENAME), getLocalConfigFilePath(mainFile), getSecretsFilePath(mainFile)} {
+		if !slices.Contains(filesToWatch, optionalFile) {
+			filesToWatch = append(filesToWatch, optionalFile)
+		}
+	}
+
+	return filesToWatch
+}
+
 func GetCacheDir() string {
 	return cacheDirectory
 }
@@ -35,15 +61,17 @@ func SetCacheDir(cacheDir string) {
 }
 
 type AldevConfig struct {
+	Version    int       // the version of the config format, to allow for automatic migrations with 'aldev config migrate'
 	AppName    string    // the name of the app - beware: the key has to be "appname" in the YAML file
 	AppDesc    string    // the description of the app - beware: the key has to be "appdesc" in the YAML file
 	Languages  string    // the languages available for this app, seperated by a comma - for example: en,fr,it,de,zh,es
 	PrivateGit string    // the URL of the private git hosting, if any, without the https:// part, e.g. "my-git.my-company.com"
 	Lib        *struct { // must be filled if this project is a library
-		SrcDir         string   // where the library source code can be found
-		BinDir         string   // the directory where to find the library's compiled binary, as seen from the library source folder (srcdir)
-		WatchAlso      []string // the additional folders / files to watch when rebuilding the code
-		resolvedBinDir string   // the bin directory as seen from the project's root
+		SrcDir         string       // where the library source code can be found
+		BinDir         string       // the directory where to find the library's compiled binary, as seen from the library source folder (srcdir)
+		WatchAlso      []string     // the additional folders / files to watch when rebuilding the code
+		Watch          *WatchConfig // which of the watched files should trigger a rebuild - or not
+		resolvedBinDir string       // the bin directory as seen from the project's root
 	}
 	API *struct { // must be filled if there's an API
 		I18n  *I18nConfig // how to translate the API's outputs
@@ -53,11 +81,13 @@ type AldevConfig struct {
 			DataDir        string // where to find bootstraping data to run the app
 			BuildImage     string // the image to use for building the API in a container
 			RunImage       string // the image to use for running the API in a container
+			ConfSchema     string // the JSON Schema - in JSON or YAML - the generated API config files should comply with, as seen from the API source folder (srcdir)
 			resolvedBinDir string // the bin directory as seen from the project's root
 		}
 		LocalDev *struct {
 			Instances int               // the number of instances to deploy when running the API locally
 			WatchAlso []string          // the additional folders / files to watch when rebuilding the code
+			Watch     *WatchConfig      // which of the watched files should trigger a rebuild - or not
 			LbImage   string            // the image to use for running the API's load balancer in a container
 			DbImages  map[string]string // the images to use for running the API's database servers in a container
 		}
@@ -104,6 +134,7 @@ type AldevConfig struct {
 	}
 	CodeSwaps []*CodeSwapsConfig // Automatically, temporarily swapping bits of code
 	Jobs      []*JobConfig       // Jobs to run
+	Include   []string           // other YAML files to merge into this config, relative to this file; this file's values win
 
 	// Computed fields
 	AppNameShort string
@@ -136,14 +167,31 @@ type CodeSwapsConfig struct {
 }
 
 type JobConfig struct {
-	Description string       // a short description of the job
-	Cmds        []*CmdConfig // a list of commands to run
+	Description string             // a short description of the job
+	Cmds        []*CmdConfig       // a list of commands to run
+	Env         map[string]*string // env vars for all the commands of the job; a null value unsets the variable
+	EnvFile     string             // an env file - with KEY=VALUE lines - to read env vars from, for all the commands of the job
+	Timeout     string             // how long the whole job can last, e.g. "10m"; no limit by default
 }
 
 type CmdConfig struct {
-	Exec   string // the command to run
-	From   string // the path from which to run the command
-	FailOK bool   // if true, then the command is allowed to fail
+	Exec       string             // the command to run
+	Shell      bool               // if true, then the command is run through the shell (sh -c, or cmd /C under Windows), to allow for pipes, redirections, env assignments, && chains, globbing...
+	Script     string             // a multi-line shell script to run instead of a command - it stops at the first failing line
+	From       string             // the path from which to run the command
+	FailOK     bool               // if true, then the command is allowed to fail
+	Env        map[string]*string // env vars for this command, on top of the job's ones; a null value unsets the variable
+	EnvFile    string             // an env file - with KEY=VALUE lines - to read env vars from, for this command
+	Timeout    string             // how long each attempt to run the command can last, e.g. "30s" or "5m"; no limit by default
+	Retries    int                // how many times the command is retried if it fails or times out; 0 by default
+	RetryDelay string             // how long to wait before the 1st retry, e.g. "2s" - doubled for each new retry; 1s by default
+	RetryOn    []int              // the exit codes for which the command is retried; any failure by default
+}
+
+type WatchConfig struct {
+	Include     []string // globs of the only files to watch, e.g. "**/*.go"; all the files by default, except the ones right in the source folder
+	Exclude     []string // globs of the files & folders not to watch, e.g. "**/testdata"; "*" stays within a folder, "**" spans folders, and a glob without "/" applies at any depth
+	NoGitignore bool     // if true, then the files ignored by Git - through the .gitignore files - are watched anyway
 }
 
 type DeployEnvConfig map[string]string // deployment parameters for this environment
@@ -172,6 +220,15 @@ func GetGoAdditionalWatchedPaths() []string {
 	return Config().API.LocalDev.WatchAlso
 }
 
+// returns the rules telling which of the watched Go source files should trigger a rebuild, if any
+func GetGoWatchConfig() *WatchConfig {
+	if IsDevLibrary() {
+		return Config().Lib.Watch
+	}
+
+	return Config().API.LocalDev.Watch
+}
+
 func GetBinDir() string {
 	if IsDevLibrary() {
 		return Config().Lib.BinDir
@@ -180,17 +237,64 @@ func GetBinDir() string {
 	return Config().API.Build.BinDir
 }
 
-func ReadConfig(cfgFileName string) {
+// ReadConfig reads the given Aldev config file - along with the files it includes, its local overlay, etc. - and
+// checks it; the previous config, if any, is kept if an error is returned, which can wrap a *ConfigError
+func ReadConfig(cfgFileName string) (err error) {
 	Debug("Reading Aldev config")
 
+	// keeping the previous config, if this one cannot be read
+	previousConfig, previousConfigFiles := config, configFiles
+	defer func() {
+		if err != nil {
+			config, configFiles = previousConfig, previousConfigFiles
+		}
+	}()
+	defer catchPanic(&err, "reading the Aldev config '%s'", cfgFileName)
+
 	config = &AldevConfig{}
 
-	// Reading the config file into bytes
-	yamlBytes, errRead := os.ReadFile(cfgFileName)
-	core.PanicIfErr(errRead)
+	// Resolving the ${VAR} references, from the environment or a .env file
+	checker := newConfigChecker()
+	lookup, envFilePath := getConfigVarLookup(cfgFileName)
 
-	// Unmarshalling the YAML file
-	core.PanicIfErr(yaml.Unmarshal(yamlBytes, config))
+	// Reading the config file, along with the files it includes
+	configFiles = []string{}
+	root := checker.readConfigFile(cfgFileName, lookup, map[string]bool{})
+
+	// Reading the local overlay, which is specific to each developer
+	if localFilePath := getLocalConfigFilePath(cfgFileName); core.FileExists(localFilePath) {
+		Debug("Reading the local Aldev config overlay: %s", localFilePath)
+		root = mergeConfigNodes(root, checker.readConfigFile(localFilePath, lookup, map[string]bool{}))
+	}
+
+	// Keeping track of all the files the config comes from
+	if envFilePath != "" {
+		configFiles = append(configFiles, envFilePath)
+	}
+	if secretsFilePath := getSecretsFilePath(cfgFileName); core.FileExists(secretsFilePath) {
+		configFiles = append(configFiles, secretsFilePath)
+	}
+	localSecrets = nil  // the secrets will be read again when needed
+	apiConfSchema = nil // same for the API config schema
+
+	// Unmarshalling the YAML, if it's structurally OK, and checking what's required by the dev modes
+	if len(checker.issues) == 0 && root != nil {
+		clearConfigMergeDirectives(root, reflect.TypeOf(config))
+		core.PanicIfErr(root.Decode(config))
+		checker.checkRequirements(config)
+	}
+
+	// Not going any further with an invalid config
+	if len(checker.issues) > 0 {
+		panic(&ConfigError{File: cfgFileName, Issues: checker.report()})
+	}
+	interpolatedValues = checker.interpol
+
+	// Telling when the config format is behind
+	if config.Version < currentConfigVersion {
+		Warn("'%s' is at config version %d, while the current one is %d; run 'aldev config migrate' to update it",
+			cfgFileName, config.Version, currentConfigVersion)
+	}
 
 	// Adding the languages to the env vars for the web
 	if config.Web != nil {
@@ -217,6 +321,133 @@ func ReadConfig(cfgFileName string) {
 	}
 	config.AppNameKebab = core.PascalToKebab(config.AppName)
 	config.AppNameLower = strings.ToLower(config.AppName)
+
+	return nil
+}
+
+// reads a config file, checks it, and merges it on top of the files it includes
+func (checker *configChecker) readConfigFile(filePath string, lookup func(string) (string, bool), including map[string]bool) *yaml.Node {
+	// no infinite inclusion loop
+	if including[filePath] {
+		checker.addAtPosition(nil, "'%s' is included by one of the files it includes", filePath)
+		return nil
+	}
+	including[filePath] = true
+	defer delete(including, filePath)
+
+	// keeping track of the files the config comes from
+	if !core.InSlice(configFiles, filePath) {
+		configFiles = append(configFiles, filePath)
+	}
+
+	// Reading the config file into bytes
+	yamlBytes, errRead := os.ReadFile(filePath)
+	core.PanicMsgIfErr(errRead, "Could not read the Aldev config file '%s'", filePath)
+
+	// Parsing the YAML file, keeping the positions of each node
+	root := &yaml.Node{}
+	core.PanicMsgIfErr(yaml.Unmarshal(yamlBytes, root), "Could not parse the Aldev config file '%s'", filePath)
+	if len(root.Content) == 0 {
+		return nil // empty file
+	}
+
+	// Not reading a config written for a newer Aldev, which could be misunderstood
+	if errMsg := checkConfigVersionKnown(root.Content[0]); errMsg != "" {
+		checker.file = filePath
+		checker.addAt(getYAMLValue(root.Content[0], "version"), "%s", errMsg)
+		return nil
+	}
+
+	// Bringing the file to the current config format, in memory
+	applied, errMigrate := migrateConfigNode(root.Content[0])
+	if errMigrate != nil {
+		checker.file = filePath
+		checker.addAt(root.Content[0], "%v", errMigrate)
+		return nil
+	}
+	if len(applied) > 0 {
+		Warn("'%s' is written in an older config format (%s); run 'aldev config migrate' to update it", filePath, strings.Join(applied, "; "))
+	}
+
+	// Resolving the ${VAR} references, and checking the file's structure: unknown keys, wrong types...
+	checker.file = filePath
+	checker.interpolate(root, lookup, "")
+	checker.checkNode(root, reflect.TypeOf(config), "")
+
+	// Merging the included files first, so that the current file has the last word
+	var merged *yaml.Node
+	for _, include := range getConfigIncludes(root.Content[0]) {
+		merged = mergeConfigNodes(merged, checker.readConfigFile(path.Join(path.Dir(filePath), include), lookup, including))
+	}
+
+	return mergeConfigNodes(merged, root)
+}
+
+// returns the files included by the given root mapping node
+func getConfigIncludes(root *yaml.Node) (includes []string) {
+	if includeNode := getYAMLValue(root, "include"); includeNode != nil {
+		_ = includeNode.Decode(&includes) // a wrong format is reported when checking the file
+	}
+
+	return
+}
+
+// returns the path to the local overlay of the given config file, e.g. ".aldev.local.yaml" for ".aldev.yaml"
+func getLocalConfigFilePath(cfgFileName string) string {
+	extension := path.Ext(cfgFileName)
+	return strings.TrimSuffix(cfgFileName, extension) + ".local" + extension
+}
+
+// merges the config node B on top of config node A, any of them possibly being nil
+func mergeConfigNodes(a, b *yaml.Node) *yaml.Node {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	return mergeYAMLNodes(a, b)
+}
+
+// removes the merge directives left in the typed parts of the config, i.e. not in the raw YAML nodes,
+// which keep them until the API configs are built
+func clearConfigMergeDirectives(node *yaml.Node, typ reflect.Type) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == yamlNodeType || node.Kind == yaml.AliasNode {
+		return
+	}
+
+	// going down the tree, along with the types - the type mismatches have been reported when checking the files
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			clearConfigMergeDirectives(child, typ)
+		}
+
+	case yaml.MappingNode:
+		removeDeletedKeys(node)
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			if typ.Kind() == reflect.Map {
+				clearConfigMergeDirectives(node.Content[i+1], typ.Elem())
+			} else if field, found := yamlFieldsOf(typ)[node.Content[i].Value]; found && typ.Kind() == reflect.Struct {
+				clearConfigMergeDirectives(node.Content[i+1], field.Type)
+			}
+		}
+
+	case yaml.SequenceNode:
+		if typ.Kind() == reflect.Slice {
+			for _, child := range node.Content {
+				clearConfigMergeDirectives(child, typ.Elem())
+			}
+		}
+	}
+
+	if getMergeDirective(node) != "" {
+		*node = *withoutMergeDirective(node)
+	}
 }
 
 // computed property on an Aldev config object
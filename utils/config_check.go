@@ -0,0 +1,416 @@
+// ----------------------------------------------------------------------------
+// The code here is about checking the Aldev config file, before anything runs
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// ----------------------------------------------------------------------------
+// Config issues
+// ----------------------------------------------------------------------------
+
+// a position in one of the config files
+type configPosition struct {
+	file   string
+	line   int
+	column int
+}
+
+// a problem found in the config files, with its position, when known
+type configIssue struct {
+	configPosition
+	msg string
+}
+
+func (issue *configIssue) String() string {
+	if issue.line > 0 {
+		return fmt.Sprintf("%s, line %d, column %d: %s", issue.file, issue.line, issue.column, issue.msg)
+	}
+
+	return issue.msg
+}
+
+// gathers all the issues found while checking the config files
+type configChecker struct {
+	issues    []*configIssue
+	file      string                        // the file currently being checked
+	positions map[string]*configPosition    // the first position of each dotted path found in the files, e.g. "api.localdev"
+	interpol  map[string]*interpolatedValue // the values obtained by interpolation, by dotted path
+}
+
+func newConfigChecker() *configChecker {
+	return &configChecker{positions: map[string]*configPosition{}, interpol: map[string]*interpolatedValue{}}
+}
+
+// adds an issue located at the given node, in the file currently being checked
+func (checker *configChecker) addAt(node *yaml.Node, msg string, params ...any) {
+	var position *configPosition
+	if node != nil {
+		position = &configPosition{file: checker.file, line: node.Line, column: node.Column}
+	}
+	checker.addAtPosition(position, msg, params...)
+}
+
+// adds an issue located at the given position
+func (checker *configChecker) addAtPosition(position *configPosition, msg string, params ...any) {
+	issue := &configIssue{msg: fmt.Sprintf(msg, params...)}
+	if position != nil {
+		issue.configPosition = *position
+	}
+	checker.issues = append(checker.issues, issue)
+}
+
+// adds an issue about the given dotted path, located at the closest existing node in the files
+func (checker *configChecker) addFor(dottedPath string, msg string, params ...any) {
+	for currentPath := dottedPath; ; currentPath = currentPath[:strings.LastIndex(currentPath, ".")] {
+		if position := checker.positions[currentPath]; position != nil {
+			checker.addAtPosition(position, msg, params...)
+			return
+		}
+		if !strings.Contains(currentPath, ".") {
+			break
+		}
+	}
+
+	checker.addAtPosition(nil, msg, params...)
+}
+
+// returns the issues, sorted by position - following the order in which the files have been read
+func (checker *configChecker) report() []string {
+	fileRank := func(issue *configIssue) int {
+		if issue.file == "" {
+			return len(configFiles)
+		}
+		return slices.Index(configFiles, issue.file)
+	}
+
+	sort.SliceStable(checker.issues, func(i, j int) bool {
+		if fileRank(checker.issues[i]) != fileRank(checker.issues[j]) {
+			return fileRank(checker.issues[i]) < fileRank(checker.issues[j])
+		}
+		if checker.issues[i].line != checker.issues[j].line {
+			return checker.issues[i].line < checker.issues[j].line
+		}
+		return checker.issues[i].column < checker.issues[j].column
+	})
+
+	lines := make([]string, len(checker.issues))
+	for i, issue := range checker.issues {
+		lines[i] = issue.String()
+	}
+
+	return lines
+}
+
+// ----------------------------------------------------------------------------
+// Checking the structure of the YAML file
+// ----------------------------------------------------------------------------
+
+var yamlNodeType = reflect.TypeOf(yaml.Node{})
+
+// checks that the given node can be decoded into the given type, with no unknown keys
+func (checker *configChecker) checkNode(node *yaml.Node, typ reflect.Type, dottedPath string) {
+	// unwrapping documents & aliases
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return
+		}
+		node = node.Content[0]
+	}
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	// keeping track of where we are
+	if _, alreadyFound := checker.positions[dottedPath]; dottedPath != "" && !alreadyFound {
+		checker.positions[dottedPath] = &configPosition{file: checker.file, line: node.Line, column: node.Column}
+	}
+
+	// a null value is always OK
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	// a merge directive is checked on the value it bears, except a deletion, which can be anything
+	if directive := getMergeDirective(node); directive == mergeDirectiveDELETE {
+		return
+	} else if directive != "" {
+		node = withoutMergeDirective(node)
+	}
+
+	// going through the pointers
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	// raw YAML nodes can contain anything
+	if typ == yamlNodeType {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if checker.expectKind(node, yaml.MappingNode, dottedPath) {
+			checker.checkStructFields(node, typ, dottedPath)
+		}
+
+	case reflect.Map:
+		if checker.expectKind(node, yaml.MappingNode, dottedPath) {
+			for i := 0; i < len(node.Content)-1; i += 2 {
+				checker.checkNode(node.Content[i+1], typ.Elem(), joinPath(dottedPath, node.Content[i].Value))
+			}
+		}
+
+	case reflect.Slice:
+		if checker.expectKind(node, yaml.SequenceNode, dottedPath) {
+			for i, item := range node.Content {
+				checker.checkNode(item, typ.Elem(), joinPath(dottedPath, fmt.Sprintf("%d", i)))
+			}
+		}
+
+	default:
+		if checker.expectKind(node, yaml.ScalarNode, dottedPath) {
+			if errDecode := node.Decode(reflect.New(typ).Interface()); errDecode != nil {
+				checker.addAt(node, "'%s' should be of type %s, not '%s'", dottedPath, typ.Kind(), node.Value)
+			}
+		}
+	}
+}
+
+// checks all the keys of a mapping node against the fields of the given struct type
+func (checker *configChecker) checkStructFields(node *yaml.Node, typ reflect.Type, dottedPath string) {
+	knownFields := yamlFieldsOf(typ)
+
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+
+		// YAML merge keys are resolved by the YAML decoder
+		if keyNode.Value == "<<" {
+			continue
+		}
+
+		field, known := knownFields[keyNode.Value]
+		if !known {
+			suggestion := ""
+			if _, knownLowered := knownFields[strings.ToLower(keyNode.Value)]; knownLowered {
+				suggestion = fmt.Sprintf(" - did you mean '%s'? (keys are all lowercase)", strings.ToLower(keyNode.Value))
+			}
+			checker.addAt(keyNode, "unknown key '%s'%s", joinPath(dottedPath, keyNode.Value), suggestion)
+			continue
+		}
+
+		checker.checkNode(valueNode, field.Type, joinPath(dottedPath, keyNode.Value))
+	}
+}
+
+// checks the node has the expected kind, and reports an issue otherwise
+func (checker *configChecker) expectKind(node *yaml.Node, kind yaml.Kind, dottedPath string) bool {
+	if node.Kind != kind {
+		checker.addAt(node, "'%s' should be a %s", core.IfThenElse(dottedPath == "", "<root>", dottedPath), kindName(kind))
+		return false
+	}
+
+	return true
+}
+
+// the YAML keys of the given struct type - which are the lowercased names of the exported fields, if no tag is used
+func yamlFieldsOf(typ reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := strings.ToLower(field.Name)
+		if tag := strings.Split(field.Tag.Get("yaml"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			key = tag
+		}
+		fields[key] = field
+	}
+
+	return fields
+}
+
+func kindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "list"
+	default:
+		return "single value"
+	}
+}
+
+func joinPath(dottedPath, key string) string {
+	if dottedPath == "" {
+		return key
+	}
+
+	return dottedPath + "." + key
+}
+
+// ----------------------------------------------------------------------------
+// Checking the required fields, depending on the development modes
+// ----------------------------------------------------------------------------
+
+// checks that the given config - which is the one currently loaded - has everything needed by its dev modes
+func (checker *configChecker) checkRequirements(cfg *AldevConfig) {
+	if cfg.AppName == "" {
+		checker.addFor("appname", "'appname' is required")
+	}
+
+	// a library
+	if cfg.Lib != nil && !IsDevLibrary() {
+		checker.addFor("lib.srcdir", "'lib.srcdir' is required when there's a 'lib' section")
+	}
+	if cfg.Lib != nil && cfg.Lib.Watch != nil {
+		checker.checkWatch(cfg.Lib.Watch, "lib.watch")
+	}
+
+	// an API needs quite a few things
+	if cfg.API != nil {
+		if !IsDevAPI() {
+			checker.addFor("api.build.srcdir", "'api.build.srcdir' is required when there's an 'api' section")
+		} else if cfg.API.Build.BinDir == "" {
+			checker.addFor("api.build.bindir", "'api.build.bindir' is required when developing an API")
+		} else if schemaPath := getAPIConfSchemaPath(); schemaPath != "" && !core.FileExists(schemaPath) {
+			checker.addFor("api.build.confschema", "the API config schema '%s' does not exist", schemaPath)
+		}
+		if cfg.API.LocalDev == nil {
+			checker.addFor("api.localdev", "'api.localdev' is required when developing an API")
+		} else if cfg.API.LocalDev.Watch != nil {
+			checker.checkWatch(cfg.API.LocalDev.Watch, "api.localdev.watch")
+		}
+		if cfg.API.Doc == nil {
+			checker.addFor("api.doc", "'api.doc' is required when developing an API")
+		}
+		if cfg.API.Runtimes == nil {
+			checker.addFor("api.runtimes", "'api.runtimes' is required when developing an API")
+		} else {
+			if cfg.API.Runtimes.Common == nil {
+				checker.addFor("api.runtimes.common", "'api.runtimes.common' is required when developing an API")
+			}
+			if cfg.API.Runtimes.Local == nil {
+				checker.addFor("api.runtimes.local", "'api.runtimes.local' is required when developing an API")
+			}
+		}
+		if cfg.API.I18n != nil {
+			checker.checkI18n(cfg, cfg.API.I18n, "api.i18n")
+		}
+	}
+
+	// a web app
+	if cfg.Web != nil {
+		if !IsDevWebApp() {
+			checker.addFor("web.srcdir", "'web.srcdir' is required when there's a 'web' section")
+		}
+		for i, envVar := range cfg.Web.EnvVars {
+			if !strings.HasPrefix(envVar.Name, "WEB_") {
+				checker.addFor(fmt.Sprintf("web.envvars.%d.name", i), "web env var '%s' should start with 'WEB_'", envVar.Name)
+			}
+		}
+	}
+
+	// a native app
+	if cfg.Native != nil {
+		if !IsDevNative() {
+			checker.addFor("native.srcdir", "'native.srcdir' is required when there's a 'native' section")
+		}
+		if cfg.Native.I18n == nil {
+			checker.addFor("native.i18n", "'native.i18n' is required when developing a native app")
+		} else {
+			checker.checkI18n(cfg, cfg.Native.I18n, "native.i18n")
+		}
+	}
+
+	// the vendored libraries
+	for i, vendor := range cfg.Vendors {
+		if !strings.Contains(vendor.Repo, "/") {
+			checker.addFor(fmt.Sprintf("vendors.%d.repo", i), "vendor repo '%s' should look like 'host/path/to/repo'", vendor.Repo)
+		}
+		if vendor.To == "" {
+			checker.addFor(fmt.Sprintf("vendors.%d.to", i), "'vendors.%d.to' is required", i)
+		}
+	}
+
+	// the jobs
+	for i, job := range cfg.Jobs {
+		if job.EnvFile != "" && !core.FileExists(job.EnvFile) {
+			checker.addFor(fmt.Sprintf("jobs.%d.envfile", i), "the env file '%s' does not exist", job.EnvFile)
+		}
+		checker.checkDuration(job.Timeout, fmt.Sprintf("jobs.%d.timeout", i))
+		for j, cmd := range job.Cmds {
+			checker.checkDuration(cmd.Timeout, fmt.Sprintf("jobs.%d.cmds.%d.timeout", i, j))
+			checker.checkDuration(cmd.RetryDelay, fmt.Sprintf("jobs.%d.cmds.%d.retrydelay", i, j))
+			if cmd.Retries < 0 {
+				checker.addFor(fmt.Sprintf("jobs.%d.cmds.%d.retries", i, j), "'jobs.%d.cmds.%d.retries' cannot be negative", i, j)
+			}
+			if cmd.EnvFile != "" && !core.FileExists(cmd.EnvFile) {
+				checker.addFor(fmt.Sprintf("jobs.%d.cmds.%d.envfile", i, j), "the env file '%s' does not exist", cmd.EnvFile)
+			}
+			hasExec, hasScript := strings.TrimSpace(cmd.Exec) != "", strings.TrimSpace(cmd.Script) != ""
+			if !hasExec && !hasScript {
+				checker.addFor(fmt.Sprintf("jobs.%d.cmds.%d", i, j), "'jobs.%d.cmds.%d' requires either 'exec' or 'script'", i, j)
+			} else if hasExec && hasScript {
+				checker.addFor(fmt.Sprintf("jobs.%d.cmds.%d.script", i, j), "'jobs.%d.cmds.%d' cannot have both 'exec' and 'script'", i, j)
+			}
+		}
+	}
+
+	// the deployment
+	if cfg.Deploying != nil {
+		if cfg.Deploying.CICD != nil && !core.InSlice(cicdTypes, cfg.Deploying.CICD.Type) {
+			checker.addFor("deploying.cicd.type", "'deploying.cicd.type' should be one of: %s", strings.Join(cicdTypes, ", "))
+		}
+		if platformTypes := core.GetSortedKeys(remoteDeploymentGeneratorRegistry.generators); cfg.Deploying.Platform != nil &&
+			!core.InSlice(platformTypes, cfg.Deploying.Platform.Type) {
+			checker.addFor("deploying.platform.type", "'deploying.platform.type' should be one of: %s", strings.Join(platformTypes, ", "))
+		}
+	}
+}
+
+// checks a duration, like "30s" or "5m", if there's one
+func (checker *configChecker) checkDuration(value string, dottedPath string) {
+	if _, errParse := time.ParseDuration(value); value != "" && errParse != nil {
+		checker.addFor(dottedPath, "'%s' should be a duration, like '30s' or '5m', not '%s'", dottedPath, value)
+	}
+}
+
+// checks the globs of a watch config
+func (checker *configChecker) checkWatch(watchCfg *WatchConfig, dottedPath string) {
+	checkGlobs := func(key string, globs []string) {
+		for i, glob := range globs {
+			if !isValidGlob(glob) {
+				checker.addFor(fmt.Sprintf("%s.%s.%d", dottedPath, key, i), "invalid glob in '%s.%s': '%s'", dottedPath, key, glob)
+			}
+		}
+	}
+	checkGlobs("include", watchCfg.Include)
+	checkGlobs("exclude", watchCfg.Exclude)
+}
+
+// checks an i18n config
+func (checker *configChecker) checkI18n(cfg *AldevConfig, i18nCfg *I18nConfig, dottedPath string) {
+	if len(i18nCfg.Links) == 0 {
+		checker.addFor(dottedPath+".links", "'%s.links' should contain at least 1 link", dottedPath)
+	}
+	if cfg.Languages == "" {
+		checker.addFor("languages", "'languages' is required when there's an '%s' section", dottedPath)
+	}
+}
+
+// the handled CI/CD types
+var cicdTypes = []string{"gitlab"}
@@ -0,0 +1,123 @@
+// ----------------------------------------------------------------------------
+// The code here is about comparing the API runtime configs of 2 environments,
+// as they would be generated, to review what actually differs between them
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// GetAPIConfDiff returns the differences between the API runtime configs of the 2 given environments, as they
+// would be generated - but with the secrets, the interpolated values & the sensitive keys' values masked - with 1 line
+// per added (+), removed (-) or changed (~) path; an error wrapping ErrUnknownEnv is returned for an unknown environment
+func GetAPIConfDiff(envA, envB string) (diff []string, err error) {
+	defer catchPanic(&err, "comparing the API configs of '%s' and '%s'", envA, envB)
+
+	if !IsDevAPI() {
+		return nil, errors.New("There's no API runtime config to compare, since no API is configured")
+	}
+
+	// checking the environments
+	envNames := getAPIEnvNames()
+	for _, envName := range []string{envA, envB} {
+		if !core.InSlice(envNames, envName) {
+			return nil, fmt.Errorf("%w '%s'; available ones: %s", ErrUnknownEnv, envName, strings.Join(envNames, ", "))
+		}
+	}
+
+	// building & flattening the configs
+	ensureLocalEnvType()
+	flatA := flattenAPIConf(envA)
+	flatB := flattenAPIConf(envB)
+
+	// all the paths, in order
+	allPaths := map[string]bool{}
+	for path := range flatA {
+		allPaths[path] = true
+	}
+	for path := range flatB {
+		allPaths[path] = true
+	}
+	paths := make([]string, 0, len(allPaths))
+	for path := range allPaths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// comparing
+	diff = []string{}
+	for _, path := range paths {
+		valueA, inA := flatA[path]
+		valueB, inB := flatB[path]
+		switch {
+		case !inA:
+			diff = append(diff, fmt.Sprintf("+ %s: %s", path, valueB))
+		case !inB:
+			diff = append(diff, fmt.Sprintf("- %s: %s", path, valueA))
+		case valueA != valueB:
+			diff = append(diff, fmt.Sprintf("~ %s: %s => %s", path, valueA, valueB))
+		}
+	}
+
+	return diff, nil
+}
+
+// returns the API config of the given env as a map of paths (e.g. "base.db.hosts[0]") to values
+func flattenAPIConf(envName string) map[string]string {
+	conf := buildAPIConf(envName, getAPIRuntimeConfig(envName), secretHandlingMASK)
+
+	// going through a generic structure, to have the aliases & merge keys resolved
+	confBytes, errMarsh := yaml.Marshal(conf)
+	core.PanicMsgIfErr(errMarsh, "Could not marshal the API config for env '%s'", envName)
+	var generic any
+	core.PanicMsgIfErr(yaml.Unmarshal(confBytes, &generic), "Could not unmarshal the API config for env '%s'", envName)
+
+	flat := map[string]string{}
+	flattenValue("", generic, flat)
+
+	return flat
+}
+
+func flattenValue(path string, value any, flat map[string]string) {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		if len(typedValue) == 0 {
+			flat[path] = "{}"
+		}
+		for key, child := range typedValue {
+			flattenValue(joinPath(path, key), child, flat)
+		}
+
+	case map[any]any:
+		if len(typedValue) == 0 {
+			flat[path] = "{}"
+		}
+		for key, child := range typedValue {
+			flattenValue(joinPath(path, fmt.Sprintf("%v", key)), child, flat)
+		}
+
+	case []any:
+		if len(typedValue) == 0 {
+			flat[path] = "[]"
+		}
+		for i, child := range typedValue {
+			flattenValue(fmt.Sprintf("%s[%d]", path, i), child, flat)
+		}
+
+	case nil:
+		flat[path] = "null"
+
+	case string:
+		flat[path] = fmt.Sprintf("%q", typedValue)
+
+	default:
+		flat[path] = fmt.Sprintf("%v", typedValue)
+	}
+}
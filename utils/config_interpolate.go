@@ -0,0 +1,85 @@
+// ----------------------------------------------------------------------------
+// The code here is about resolving the ${VAR} references in the config file,
+// from the environment, or from a .env file next to the config file
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// the name of the optional env file, looked for next to the config file
+const configEnvFILENAME = ".env"
+
+// a value obtained by interpolation, with the original value - i.e. with the ${VAR} references - it comes from
+type interpolatedValue struct {
+	value    string
+	original string
+}
+
+// the values of the current config obtained by interpolation - which may bear credentials - by dotted path, e.g.
+// "api.runtimes.common.base.db.pass"
+var interpolatedValues map[string]*interpolatedValue
+
+// returns a function to look up variables, first in the process environment, then in the .env file next to the config file, if any
+func getConfigVarLookup(cfgFileName string) (lookup func(string) (string, bool), envFilePath string) {
+	envFileVars := map[string]string{}
+	if envFilePath = path.Join(path.Dir(cfgFileName), configEnvFILENAME); core.FileExists(envFilePath) {
+		Debug("Reading the variables from: %s", envFilePath)
+		envFileVars = readEnvFile(envFilePath)
+	} else {
+		envFilePath = ""
+	}
+
+	return func(name string) (string, bool) {
+		if value, found := os.LookupEnv(name); found {
+			return value, true
+		}
+		value, found := envFileVars[name]
+		return value, found
+	}, envFilePath
+}
+
+// replaces the ${VAR} references in all the scalar values of the given node - found at the given dotted path - and
+// reports the unresolved ones
+func (checker *configChecker) interpolate(node *yaml.Node, lookup func(string) (string, bool), dottedPath string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			checker.interpolate(child, lookup, dottedPath)
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			checker.interpolate(item, lookup, joinPath(dottedPath, fmt.Sprintf("%d", i)))
+		}
+
+	case yaml.MappingNode:
+		// only the values are interpolated, not the keys
+		for i := 1; i < len(node.Content); i += 2 {
+			checker.interpolate(node.Content[i], lookup, joinPath(dottedPath, node.Content[i-1].Value))
+		}
+
+	case yaml.ScalarNode:
+		interpolated, unresolved := interpolateEnvVars(node.Value, lookup)
+		for _, varName := range unresolved {
+			checker.addAt(node, "unresolved variable '%s' - set it in the environment or in the '%s' file", varName, configEnvFILENAME)
+		}
+		if interpolated != node.Value {
+			// the files are read before the ones they include, over which they have the last word
+			if _, alreadyFound := checker.interpol[dottedPath]; interpolated != "" && !alreadyFound {
+				checker.interpol[dottedPath] = &interpolatedValue{value: interpolated, original: node.Value}
+			}
+			node.Value = interpolated
+			// letting the YAML decoder guess the type again - e.g. a port - if the value is not quoted
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// ----------------------------------------------------------------------------
+// The code here is about migrating the config files written for older
+// versions of Aldev into the current format
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// the current version of the config format; to increment with each new migration
+const currentConfigVersion = 2
+
+// a migration brings a config file to the given version
+type configMigration struct {
+	toVersion   int                                 // the version reached with this migration
+	description string                              // what's done by this migration
+	apply       func(root *yaml.Node) (bool, error) // applies the migration on the root mapping node; returns true if something changed
+}
+
+// all the migrations, in order
+var configMigrations = []*configMigration{
+	{
+		toVersion:   1,
+		description: "removing the 'apionly' key, which is not used anymore - an API-only project has no 'web' section",
+		apply: func(root *yaml.Node) (bool, error) {
+			// the web section was ignored with 'apionly: true', so it's kept as a comment only
+			if apiOnly := getYAMLValue(root, "apionly"); apiOnly != nil && apiOnly.Value == "true" {
+				commentOutYAMLKey(root, []string{"web"}, "disabled by the former 'apionly: true'")
+			}
+			return removeYAMLKey(root, "apionly"), nil
+		},
+	},
+	{
+		toVersion:   1,
+		description: "removing the 'api.doc.openin' & 'api.doc.report' keys, which are not used anymore",
+		apply: func(root *yaml.Node) (bool, error) {
+			removedOpenIn := removeYAMLKey(root, "api", "doc", "openin")
+			removedReport := removeYAMLKey(root, "api", "doc", "report")
+			return removedOpenIn || removedReport, nil
+		},
+	},
+	{
+		toVersion:   2,
+		description: "moving the API's build & local dev settings from right under 'api' into 'api.build' & 'api.localdev'",
+		apply: func(root *yaml.Node) (bool, error) {
+			moved := false
+			for _, key := range []string{"srcdir", "bindir", "datadir", "buildimage", "runimage"} {
+				movedKey, errMove := moveYAMLKey(root, []string{"api", key}, []string{"api", "build", key})
+				if errMove != nil {
+					return moved, errMove
+				}
+				moved = movedKey || moved
+			}
+			for _, key := range []string{"instances", "watchalso", "lbimage", "dbimages"} {
+				movedKey, errMove := moveYAMLKey(root, []string{"api", key}, []string{"api", "localdev", key})
+				if errMove != nil {
+					return moved, errMove
+				}
+				moved = movedKey || moved
+			}
+			return moved, nil
+		},
+	},
+}
+
+// ----------------------------------------------------------------------------
+// Migrating in memory
+// ----------------------------------------------------------------------------
+
+// returns the version of the config format declared in the given root mapping node - 0 if there's none
+func getConfigVersion(root *yaml.Node) int {
+	if versionNode := getYAMLValue(root, "version"); versionNode != nil {
+		version, errConv := strconv.Atoi(versionNode.Value)
+		if errConv == nil {
+			return version
+		}
+	}
+
+	return 0
+}
+
+// returns an error message if the given root mapping node is written in a config format newer than this Aldev's one
+func checkConfigVersionKnown(root *yaml.Node) string {
+	if version := getConfigVersion(root); version > currentConfigVersion {
+		return fmt.Sprintf("the config format version %d is newer than the one this Aldev understands (%d); please upgrade Aldev",
+			version, currentConfigVersion)
+	}
+
+	return ""
+}
+
+// applies the needed migrations on the given root mapping node, and returns the descriptions of the ones that changed
+// something, or the error of the first one that could not be applied
+func migrateConfigNode(root *yaml.Node) (applied []string, err error) {
+	fromVersion := getConfigVersion(root)
+	for _, migration := range configMigrations {
+		if migration.toVersion <= fromVersion {
+			continue
+		}
+		changed, errApply := migration.apply(root)
+		if errApply != nil {
+			return applied, fmt.Errorf("could not apply the migration %s: %w", migration.description, errApply)
+		}
+		if changed {
+			applied = append(applied, migration.description)
+		}
+	}
+
+	return applied, nil
+}
+
+// ----------------------------------------------------------------------------
+// Migrating the files
+// ----------------------------------------------------------------------------
+
+// MigrateConfigFiles rewrites the given config file - along with the files it includes, and its local overlay -
+// into the current config format, keeping the comments
+func MigrateConfigFiles(cfgFileName string) (err error) {
+	defer catchPanic(&err, "migrating the config files")
+
+	// the main file
+	if errMain := migrateConfigFile(cfgFileName, true, map[string]bool{}); errMain != nil {
+		return errMain
+	}
+
+	// the local overlay
+	if localFilePath := getLocalConfigFilePath(cfgFileName); core.FileExists(localFilePath) {
+		return migrateConfigFile(localFilePath, false, map[string]bool{})
+	}
+
+	return nil
+}
+
+// migrates 1 config file, and the ones it includes
+func migrateConfigFile(filePath string, isMain bool, done map[string]bool) error {
+	// dealing with each file only once
+	if done[filePath] {
+		return nil
+	}
+	done[filePath] = true
+
+	// reading the file
+	yamlBytes, errRead := os.ReadFile(filePath)
+	if errRead != nil {
+		return fmt.Errorf("Could not read the Aldev config file '%s': %w", filePath, errRead)
+	}
+	doc := &yaml.Node{}
+	if errParse := yaml.Unmarshal(yamlBytes, doc); errParse != nil {
+		return fmt.Errorf("Could not parse the Aldev config file '%s': %w", filePath, errParse)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		Info("Nothing to migrate in: %s", filePath)
+		return nil
+	}
+	root := doc.Content[0]
+
+	// not downgrading a config written for a newer Aldev
+	if errMsg := checkConfigVersionKnown(root); errMsg != "" {
+		return fmt.Errorf("Cannot migrate '%s': %s", filePath, errMsg)
+	}
+
+	// migrating the included files first
+	for _, include := range getConfigIncludes(root) {
+		if errInclude := migrateConfigFile(path.Join(path.Dir(filePath), include), false, done); errInclude != nil {
+			return errInclude
+		}
+	}
+
+	// migrating
+	fromVersion := getConfigVersion(root)
+	applied, errMigrate := migrateConfigNode(root)
+	if errMigrate != nil {
+		return fmt.Errorf("Cannot migrate '%s': %w", filePath, errMigrate)
+	}
+	versionChanged := isMain && fromVersion != currentConfigVersion
+	if versionChanged {
+		setConfigVersion(root, currentConfigVersion)
+	}
+
+	// writing out the file, if needed
+	if len(applied) == 0 && !versionChanged {
+		Info("Already up-to-date: %s", filePath)
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(detectYAMLIndent(yamlBytes))
+	core.PanicMsgIfErr(encoder.Encode(doc), "Could not marshal the migrated config file '%s'", filePath)
+	core.PanicIfErr(encoder.Close())
+	WriteStringToFile(filePath, "%s", buffer.String())
+
+	// logging
+	for _, description := range applied {
+		Info("Migrated %s: %s", filePath, description)
+	}
+	if versionChanged {
+		Info("Migrated %s: from version %d to version %d", filePath, fromVersion, currentConfigVersion)
+	}
+
+	return nil
+}
+
+// sets the version in the given root mapping node, adding it at the top if needed
+func setConfigVersion(root *yaml.Node, version int) {
+	if versionNode := getYAMLValue(root, "version"); versionNode != nil {
+		versionNode.Value = strconv.Itoa(version)
+		versionNode.Tag = "!!int"
+		return
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+
+	// the comment at the top of the file should stay at the top
+	if len(root.Content) > 0 {
+		keyNode.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+
+	root.Content = append([]*yaml.Node{keyNode, valueNode}, root.Content...)
+}
+
+// returns the number of spaces used for the first indented line of the given YAML content, or 2 by default
+func detectYAMLIndent(yamlBytes []byte) int {
+	for _, line := range strings.Split(string(yamlBytes), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if indent := len(line) - len(trimmed); indent > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") &&
+			!strings.HasPrefix(trimmed, "- ") {
+			return indent
+		}
+	}
+
+	return 2
+}
+
+// ----------------------------------------------------------------------------
+// Utils - YAML nodes
+// ----------------------------------------------------------------------------
+
+// returns the value node at the given path of keys, from the given mapping node, or nil
+func getYAMLValue(node *yaml.Node, keys ...string) *yaml.Node {
+	if len(keys) == 0 {
+		return node
+	}
+	_, valueNode := getYAMLKeyValue(node, keys...)
+
+	return valueNode
+}
+
+// returns the key & value nodes at the given path of keys, from the given mapping node, or nils
+func getYAMLKeyValue(node *yaml.Node, keys ...string) (keyNode, valueNode *yaml.Node) {
+	valueNode = node
+	for _, key := range keys {
+		if valueNode == nil || valueNode.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		parent := valueNode
+		keyNode, valueNode = nil, nil
+		for i := 0; i < len(parent.Content)-1; i += 2 {
+			if parent.Content[i].Value == key {
+				keyNode, valueNode = parent.Content[i], parent.Content[i+1]
+			}
+		}
+	}
+
+	return
+}
+
+// removes the key at the given path from the given mapping node; returns true if the key was there
+func removeYAMLKey(node *yaml.Node, keys ...string) bool {
+	parent := getYAMLValue(node, keys[:len(keys)-1]...)
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i < len(parent.Content)-1; i += 2 {
+		if parent.Content[i].Value == keys[len(keys)-1] {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// moves the key at the given path to the other given path - creating the missing mappings along the way - unless
+// there's already a value there, which wins; returns true if the key was there, or an error - with the given node
+// left untouched - if the target path goes through something else than a mapping
+func moveYAMLKey(node *yaml.Node, fromKeys, toKeys []string) (bool, error) {
+	keyNode, valueNode := getYAMLKeyValue(node, fromKeys...)
+	if keyNode == nil {
+		return false, nil
+	}
+
+	// checking the target path before changing anything
+	for i := 1; i < len(toKeys); i++ {
+		if ancestor := getYAMLValue(node, toKeys[:i]...); ancestor != nil && ancestor.Kind != yaml.MappingNode {
+			return false, fmt.Errorf("cannot move '%s' into '%s', which is not a mapping",
+				strings.Join(fromKeys, "."), strings.Join(toKeys[:i], "."))
+		}
+	}
+	removeYAMLKey(node, fromKeys...)
+
+	// the value set at the new location wins
+	if getYAMLValue(node, toKeys...) != nil {
+		Warn("Dropping '%s', since '%s' is already set", strings.Join(fromKeys, "."), strings.Join(toKeys, "."))
+		return true, nil
+	}
+
+	// making sure the target mapping exists
+	parent := node
+	for _, key := range toKeys[:len(toKeys)-1] {
+		child := getYAMLValue(parent, key)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		parent = child
+	}
+
+	// moving the key - with its comments - and its value
+	keyNode.Value = toKeys[len(toKeys)-1]
+	parent.Content = append(parent.Content, keyNode, valueNode)
+
+	return true, nil
+}
+
+// replaces the key at the given path of the given mapping node with a comment bearing its content - with the given
+// explanation - so that nothing's lost; returns true if the key was there
+func commentOutYAMLKey(node *yaml.Node, keys []string, explanation string) bool {
+	keyNode, valueNode := getYAMLKeyValue(node, keys...)
+	parent := getYAMLValue(node, keys[:len(keys)-1]...)
+	if keyNode == nil {
+		return false
+	}
+
+	// the content to comment out
+	buffer := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	core.PanicMsgIfErr(encoder.Encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{keyNode, valueNode}}), "Could not marshal '%s'", strings.Join(keys, "."))
+	core.PanicIfErr(encoder.Close())
+	comment := strings.Join(keys, ".") + " - " + explanation + ":"
+	for line := range strings.Lines(strings.TrimRight(buffer.String(), "\n")) {
+		comment += "\n" + strings.TrimRight(line, "\n")
+	}
+
+	// the comment goes above the next key, if any, or at the end of the mapping
+	removeYAMLKey(node, keys...)
+	for i := 0; i < len(parent.Content)-1; i += 2 {
+		if parent.Content[i].Line > keyNode.Line {
+			parent.Content[i].HeadComment = strings.TrimPrefix(parent.Content[i].HeadComment+"\n"+comment, "\n")
+			return true
+		}
+	}
+	parent.FootComment = strings.TrimPrefix(parent.FootComment+"\n"+comment, "\n")
+
+	return true
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMoveYAMLKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		wantMoved  bool
+		wantYAML   string
+		wantErrMsg string
+	}{
+		{
+			name:      "missing target mapping",
+			yaml:      "api:\n  srcdir: src\n",
+			wantMoved: true,
+			wantYAML:  "api:\n  build:\n    srcdir: src\n",
+		},
+		{
+			name:      "value already set at the target",
+			yaml:      "api:\n  srcdir: old\n  build:\n    srcdir: src\n",
+			wantMoved: true,
+			wantYAML:  "api:\n  build:\n    srcdir: src\n",
+		},
+		{
+			name:     "missing source key",
+			yaml:     "api:\n  build:\n    srcdir: src\n",
+			wantYAML: "api:\n  build:\n    srcdir: src\n",
+		},
+		{
+			name:       "target that is not a mapping",
+			yaml:       "api:\n  srcdir: src\n  build: docker\n",
+			wantYAML:   "api:\n  srcdir: src\n  build: docker\n",
+			wantErrMsg: "cannot move 'api.srcdir' into 'api.build', which is not a mapping",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := &yaml.Node{}
+			if errUnmarshal := yaml.Unmarshal([]byte(test.yaml), doc); errUnmarshal != nil {
+				t.Fatal(errUnmarshal)
+			}
+
+			moved, err := moveYAMLKey(doc.Content[0], []string{"api", "srcdir"}, []string{"api", "build", "srcdir"})
+
+			if moved != test.wantMoved {
+				t.Errorf("unexpected move: got %t, want %t", moved, test.wantMoved)
+			}
+			switch {
+			case test.wantErrMsg == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.wantErrMsg != "" && (err == nil || err.Error() != test.wantErrMsg):
+				t.Errorf("unexpected error: got %v, want %q", err, test.wantErrMsg)
+			}
+
+			// the tree should be left untouched when failing
+			buffer := new(bytes.Buffer)
+			encoder := yaml.NewEncoder(buffer)
+			encoder.SetIndent(2)
+			if errEncode := encoder.Encode(doc); errEncode != nil {
+				t.Fatal(errEncode)
+			}
+			if buffer.String() != test.wantYAML {
+				t.Errorf("unexpected YAML:\n got: %q\nwant: %q", buffer.String(), test.wantYAML)
+			}
+		})
+	}
+}
+
+func TestMigrateConfigFilesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		wantErrMsg string
+	}{
+		{
+			name:       "malformed file",
+			yaml:       "api: [srcdir\n",
+			wantErrMsg: "Could not parse the Aldev config file",
+		},
+		{
+			name:       "file from a newer Aldev",
+			yaml:       "version: 99\n",
+			wantErrMsg: "the config format version 99 is newer",
+		},
+		{
+			name:       "migration that cannot be applied",
+			yaml:       "api:\n  srcdir: src\n  build: docker\n",
+			wantErrMsg: "cannot move 'api.srcdir' into 'api.build'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfgFilePath := filepath.Join(t.TempDir(), "aldev.yaml")
+			writeFile(t, cfgFilePath, test.yaml)
+
+			err := MigrateConfigFiles(cfgFilePath)
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("unexpected error: got %v, want it to contain %q", err, test.wantErrMsg)
+			}
+			if content, _ := os.ReadFile(cfgFilePath); string(content) != test.yaml {
+				t.Errorf("the file should not have been changed, but it's now: %q", content)
+			}
+		})
+	}
+}
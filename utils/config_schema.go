@@ -0,0 +1,181 @@
+// ----------------------------------------------------------------------------
+// The code here is about describing the Aldev config file with a JSON Schema,
+// to get completion & validation in editors, or CI checks
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+)
+
+// the source code of the config structs, whose comments are used as descriptions in the schema
+//
+//go:embed config.go
+var configSourceCode string
+
+// the fields that are computed by Aldev, and thus not expected in the config file
+var computedConfigKeys = []string{"appnameshort", "appnamekebab", "appnamelower"}
+
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+}
+
+// ConfigJSONSchema returns the JSON Schema describing the Aldev config file
+func ConfigJSONSchema() []byte {
+	// the descriptions come from the comments on the config structs
+	descriptions := getConfigFieldComments()
+
+	// the root of the schema
+	schema := buildJSONSchema(reflect.TypeOf(AldevConfig{}), "", "AldevConfig", descriptions)
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = "Aldev config"
+	schema.Description = "The configuration of an Aldev project, usually found in the '.aldev.yaml' file"
+	schema.Required = []string{"appname"}
+
+	schemaBytes, errMarsh := json.MarshalIndent(schema, "", "  ")
+	core.PanicMsgIfErr(errMarsh, "Could not marshal the Aldev config JSON schema")
+
+	return schemaBytes
+}
+
+// builds the schema for the given type, found at the given YAML path, and whose Go fields are documented under the given name
+func buildJSONSchema(typ reflect.Type, dottedPath, docPath string, descriptions map[string]string) *jsonSchema {
+	// going through the pointers
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	// raw YAML nodes can contain anything
+	if typ == yamlNodeType {
+		return &jsonSchema{}
+	}
+
+	// named types are documented on their own
+	if typ.Name() != "" && typ.PkgPath() == reflect.TypeOf(AldevConfig{}).PkgPath() {
+		docPath = typ.Name()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+		for key, field := range yamlFieldsOf(typ) {
+			if dottedPath == "" && core.InSlice(computedConfigKeys, key) {
+				continue
+			}
+			fieldDocPath := docPath + "." + field.Name
+			fieldSchema := buildJSONSchema(field.Type, joinPath(dottedPath, key), fieldDocPath, descriptions)
+			fieldSchema.Description = descriptions[fieldDocPath]
+			schema.Properties[key] = fieldSchema
+		}
+		return schema
+
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: buildJSONSchema(typ.Elem(), dottedPath+".*", docPath, descriptions)}
+
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: buildJSONSchema(typ.Elem(), dottedPath+".*", docPath, descriptions)}
+
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonSchema{Type: "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+
+	default:
+		return &jsonSchema{Type: "string", Enum: getConfigEnum(dottedPath)}
+	}
+}
+
+// some config values can only take a few values
+func getConfigEnum(dottedPath string) []string {
+	switch dottedPath {
+	case "deploying.cicd.type":
+		return cicdTypes
+	case "deploying.platform.type":
+		return core.GetSortedKeys(remoteDeploymentGeneratorRegistry.generators)
+	}
+
+	return nil
+}
+
+// reads the comments of the config structs' fields, mapped by the path to the field, e.g. "AldevConfig.API.Build.SrcDir"
+func getConfigFieldComments() map[string]string {
+	fileSet := token.NewFileSet()
+	file, errParse := parser.ParseFile(fileSet, "config.go", configSourceCode, parser.ParseComments)
+	core.PanicMsgIfErr(errParse, "Could not parse the config source code")
+
+	// indexing the comments by line, to catch the ones right after an opening brace, e.g. "Lib *struct { // ..."
+	commentsByLine := map[int]string{}
+	for _, commentGroup := range file.Comments {
+		commentsByLine[fileSet.Position(commentGroup.Pos()).Line] = commentGroup.Text()
+	}
+
+	comments := map[string]string{}
+
+	var readFields func(structType *ast.StructType, docPath string)
+	readFields = func(structType *ast.StructType, docPath string) {
+		for _, field := range structType.Fields.List {
+			// the comment is either above the field, or on the same line as the field's start
+			comment := field.Doc.Text()
+			if comment == "" {
+				comment = commentsByLine[fileSet.Position(field.Pos()).Line]
+			}
+
+			for _, name := range field.Names {
+				fieldDocPath := docPath + "." + name.Name
+				comments[fieldDocPath] = strings.TrimSpace(comment)
+
+				// going into the anonymous structs
+				fieldType := field.Type
+				for {
+					if starExpr, isStar := fieldType.(*ast.StarExpr); isStar {
+						fieldType = starExpr.X
+					} else if arrayType, isArray := fieldType.(*ast.ArrayType); isArray {
+						fieldType = arrayType.Elt
+					} else if mapType, isMap := fieldType.(*ast.MapType); isMap {
+						fieldType = mapType.Value
+					} else {
+						break
+					}
+				}
+				if subStructType, isStruct := fieldType.(*ast.StructType); isStruct {
+					readFields(subStructType, fieldDocPath)
+				}
+			}
+		}
+	}
+
+	// going through all the struct types declared in the config source code
+	for _, decl := range file.Decls {
+		if genDecl, isGenDecl := decl.(*ast.GenDecl); isGenDecl && genDecl.Tok == token.TYPE {
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if structType, isStruct := typeSpec.Type.(*ast.StructType); isStruct {
+					readFields(structType, typeSpec.Name.Name)
+				}
+			}
+		}
+	}
+
+	return comments
+}
@@ -0,0 +1,122 @@
+// ----------------------------------------------------------------------------
+// The code here is about showing the configuration, as Aldev understands it
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// what's shown about the configuration: the config file's content, completed with everything Aldev computes
+type resolvedConfig struct {
+	Config   *yaml.Node          // the config, as read and completed by Aldev - with its credentials masked
+	Computed *computedConfig     // the properties computed from the config
+	APIConfs map[string]*apiConf // the resolved API config, for each environment
+}
+
+type computedConfig struct {
+	DevModes       []string // the detected development modes
+	ResolvedBinDir string   // the bin directory as seen from the project's root
+	LocalPort      int      // the port of the API, when run locally
+}
+
+// GetResolvedConfig returns the current config, along with the computed properties & resolved API configs,
+// as a YAML or JSON document. The API configs can be restricted to 1 environment; an error wrapping ErrUnknownEnv is
+// returned if it's not a configured one.
+func GetResolvedConfig(onlyEnv string, asJSON bool) (resolvedBytes []byte, err error) {
+	defer catchPanic(&err, "resolving the config")
+
+	resolved := &resolvedConfig{
+		Config:   getMaskedConfig(),
+		Computed: &computedConfig{DevModes: getDevModes()},
+	}
+
+	// the computed properties which only make sense for some dev modes
+	if IsDevGoSrc() {
+		resolved.Computed.ResolvedBinDir = Config().ResolvedBinDir()
+	}
+	if Config().API != nil {
+		resolved.Computed.LocalPort = Config().LocalPort()
+	}
+
+	// the API configs for all - or only 1 - environments
+	if IsDevAPI() {
+		envNames := getAPIEnvNames()
+		if onlyEnv != "" {
+			if !core.InSlice(envNames, onlyEnv) {
+				return nil, fmt.Errorf("%w '%s'; available ones: %s", ErrUnknownEnv, onlyEnv, strings.Join(envNames, ", "))
+			}
+			envNames = []string{onlyEnv}
+		}
+
+		ensureLocalEnvType()
+		resolved.APIConfs = map[string]*apiConf{}
+		for _, envName := range envNames {
+			resolved.APIConfs[envName] = buildAPIConf(envName, getAPIRuntimeConfig(envName), secretHandlingMASK)
+		}
+	}
+
+	// YAML by default
+	yamlBytes, errMarsh := yaml.Marshal(resolved)
+	core.PanicMsgIfErr(errMarsh, "Could not marshal the resolved config")
+	if !asJSON {
+		return yamlBytes, nil
+	}
+
+	// for JSON, going through a generic structure, since the YAML nodes cannot be JSON-marshalled as is
+	var generic map[string]any
+	core.PanicMsgIfErr(yaml.Unmarshal(yamlBytes, &generic), "Could not unmarshal the resolved config")
+	jsonBytes, errJSON := json.MarshalIndent(generic, "", "  ")
+	core.PanicMsgIfErr(errJSON, "Could not JSON-marshal the resolved config")
+
+	return jsonBytes, nil
+}
+
+// returns the current config as a YAML node, with its secrets, interpolated values & sensitive keys' values masked, and
+// without the merge directives, which are not applied here
+func getMaskedConfig() *yaml.Node {
+	node := &yaml.Node{}
+	core.PanicMsgIfErr(node.Encode(Config()), "Could not encode the config")
+
+	clearMergeDirectiveTags(node)
+	maskSensitiveValues(node, false, "")
+	resolveSecrets("", node, secretHandlingMASK)
+
+	return node
+}
+
+// removes the merge directives from the tags of the given node and its children, leaving their values as they are
+func clearMergeDirectiveTags(node *yaml.Node) {
+	if node.Kind != yaml.AliasNode {
+		for _, child := range node.Content {
+			clearMergeDirectiveTags(child)
+		}
+	}
+
+	if getMergeDirective(node) != "" {
+		*node = *withoutMergeDirective(node)
+	}
+}
+
+// the names of the detected development modes
+func getDevModes() (devModes []string) {
+	if IsDevAPI() {
+		devModes = append(devModes, "api")
+	}
+	if IsDevLibrary() {
+		devModes = append(devModes, "library")
+	}
+	if IsDevWebApp() {
+		devModes = append(devModes, "webapp")
+	}
+	if IsDevNative() {
+		devModes = append(devModes, "native")
+	}
+
+	return
+}
@@ -7,12 +7,16 @@ package utils
 import (
 	"context"
 	"io"
+	"maps"
 	"os"
 	"os/signal"
 	"path"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	core "github.com/aldesgroup/corego"
 )
 
 // ----------------------------------------------------------------------------
@@ -27,14 +31,30 @@ type CancelableContext interface {
 	getStdOutWriter() io.Writer
 	WithStdErrWriter(io.Writer) CancelableContext
 	getStdErrWriter() io.Writer
+	WithOutputPrefix(string) CancelableContext
 	// WithErrLogFn(errLogFn) CancelableContext
 	// getErrLogFn() errLogFn
 	WithEnvVars(...string) CancelableContext
+	WithoutEnvVars(...string) CancelableContext
+	WithEnvFile(string) CancelableContext
 	getEnvVars() []string
+	getEnvVarKeys() []string
 	WithReRun() CancelableContext
 	isReRun() bool
 	WithAllowFailure(bool) CancelableContext
 	isAllowingFailure() bool
+	WithTimeout(time.Duration) CancelableContext
+	getTimeout() time.Duration
+	WithTimeLimit(time.Time) CancelableContext
+	getTimeLimit() time.Time
+	WithRetries(retries int, delay time.Duration, onExitCodes ...int) CancelableContext
+	getRetryPolicy() *retryPolicy
+	WithRunner(Runner) CancelableContext
+	getRunner() Runner
+	AddWorker(name string) (done func())
+	Go(name string, workerFn func())
+	WithStopTimeout(time.Duration) CancelableContext
+	Wait() bool
 	CancelAll()
 	NewChildContext() CancelableContext
 }
@@ -50,22 +70,44 @@ type baseCancelableContext struct {
 	shortCommands bool
 	stdoutWriter  io.Writer
 	stderrWriter  io.Writer
-	envVars       []string
+	envVars       map[string]*string // the env vars set - or unset, if nil - for the commands, on top of the current process' ones
 	reRun         bool
 	allowFailure  bool
+	workers       *workerGroup  // the workers currently running with this context
+	stopTimeout   time.Duration // how long the workers are waited for, once the context is cancelled
+	timeout       time.Duration // how long each attempt to run a command can last, if > 0
+	retryPolicy   *retryPolicy  // how the failed commands are retried, if they are
+	runner        Runner        // what actually runs the commands
+	timeLimit     time.Time     // when all the attempts to run a command must be over, retry delays included, if set
 	// errLogFn      errLogFn
 }
 
 func NewBaseContext() *baseCancelableContext {
 	return &baseCancelableContext{
-		Context: context.WithoutCancel(context.Background()),
+		Context:     context.WithoutCancel(context.Background()),
+		workers:     newWorkerGroup(),
+		stopTimeout: defaultStopTimeout,
+		runner:      defaultRunner,
 	}
 }
 
 func newBaseCancelableContext() *baseCancelableContext {
 	ctx, cancelFn := context.WithCancel(context.Background())
 	// return &baseCancelableContext{ctx, cancelFn, "", false, nil, nil, nil, nil, false, false}
-	return &baseCancelableContext{ctx, cancelFn, "", false, nil, nil, nil, false, false}
+	return &baseCancelableContext{ctx, cancelFn, "", false, nil, nil, nil, false, false, newWorkerGroup(), defaultStopTimeout, 0, nil, defaultRunner, time.Time{}}
+}
+
+// returns a new cancelable context - using the same runner - which is cancelled along with the given one, and the
+// function to call once it's not needed anymore
+func newBoundContext(ctx CancelableContext) (*baseCancelableContext, func()) {
+	boundCtx := newBaseCancelableContext()
+	boundCtx.runner = ctx.getRunner()
+	stopWithCtx := context.AfterFunc(ctx, boundCtx.cancelFn)
+
+	return boundCtx, func() {
+		stopWithCtx()
+		boundCtx.cancelFn()
+	}
 }
 
 func (thisCtx *baseCancelableContext) WithExecDir(dirElems ...string) CancelableContext {
@@ -98,6 +140,14 @@ func (thisCtx *baseCancelableContext) getStdErrWriter() io.Writer {
 	return thisCtx.stderrWriter
 }
 
+// prefixes each line written by the commands run with this context with the given name, to tell them apart from the
+// other commands running at the same time
+func (thisCtx *baseCancelableContext) WithOutputPrefix(name string) CancelableContext {
+	thisCtx.stdoutWriter = NewPrefixedWriter(name, core.IfThenElse[io.Writer](thisCtx.stdoutWriter != nil, thisCtx.stdoutWriter, os.Stdout))
+	thisCtx.stderrWriter = NewPrefixedWriter(name, core.IfThenElse[io.Writer](thisCtx.stderrWriter != nil, thisCtx.stderrWriter, os.Stderr))
+	return thisCtx
+}
+
 // func (thisCtx *baseCancelableContext) WithErrLogFn(errLogFn errLogFn) CancelableContext {
 // 	thisCtx.errLogFn = errLogFn
 // 	return thisCtx
@@ -111,13 +161,72 @@ func (thisCtx *baseCancelableContext) getStdErrWriter() io.Writer {
 // 	return core.PanicMsg
 // }
 
+// sets env vars, given as KEY=VALUE, for the commands run with this context
 func (thisCtx *baseCancelableContext) WithEnvVars(envVars ...string) CancelableContext {
-	thisCtx.envVars = envVars
+	for _, envVar := range envVars {
+		key, value, found := strings.Cut(envVar, "=")
+		core.PanicMsgIf(!found || key == "", "Invalid env var '%s'; expected: KEY=VALUE", envVar)
+		thisCtx.setEnvVar(key, &value)
+	}
+
 	return thisCtx
 }
 
+// unsets env vars for the commands run with this context, even if the current process has them
+func (thisCtx *baseCancelableContext) WithoutEnvVars(keys ...string) CancelableContext {
+	for _, key := range keys {
+		thisCtx.setEnvVar(key, nil)
+	}
+
+	return thisCtx
+}
+
+// sets the env vars read from the given env file - if any - for the commands run with this context
+func (thisCtx *baseCancelableContext) WithEnvFile(filePath string) CancelableContext {
+	if filePath != "" {
+		for key, value := range readEnvFile(filePath) {
+			thisCtx.setEnvVar(key, &value)
+		}
+	}
+
+	return thisCtx
+}
+
+func (thisCtx *baseCancelableContext) setEnvVar(key string, value *string) {
+	if thisCtx.envVars == nil {
+		thisCtx.envVars = map[string]*string{}
+	}
+	thisCtx.envVars[key] = value
+}
+
+// returns the names of the env vars set - or unset - for the commands run with this context
+func (thisCtx *baseCancelableContext) getEnvVarKeys() []string {
+	return core.GetSortedKeys(thisCtx.envVars)
+}
+
+// returns the whole environment for the commands run with this context, or nil if it's just the current process' one
 func (thisCtx *baseCancelableContext) getEnvVars() []string {
-	return thisCtx.envVars
+	if len(thisCtx.envVars) == 0 {
+		return nil
+	}
+
+	// the current process' env vars, except the ones overridden or unset here
+	envVars := []string{}
+	for _, envVar := range os.Environ() {
+		key, _, _ := strings.Cut(envVar, "=")
+		if _, overridden := thisCtx.envVars[key]; !overridden {
+			envVars = append(envVars, envVar)
+		}
+	}
+
+	// the env vars set here
+	for _, key := range core.GetSortedKeys(thisCtx.envVars) {
+		if value := thisCtx.envVars[key]; value != nil {
+			envVars = append(envVars, key+"="+*value)
+		}
+	}
+
+	return envVars
 }
 
 func (thisCtx *baseCancelableContext) WithReRun() CancelableContext {
@@ -138,9 +247,98 @@ func (thisCtx *baseCancelableContext) isAllowingFailure() bool {
 	return thisCtx.allowFailure
 }
 
+// sets how long each attempt to run a command with this context can last, before the command is stopped
+func (thisCtx *baseCancelableContext) WithTimeout(timeout time.Duration) CancelableContext {
+	thisCtx.timeout = timeout
+	return thisCtx
+}
+
+func (thisCtx *baseCancelableContext) getTimeout() time.Duration {
+	return thisCtx.timeout
+}
+
+// sets when all the attempts to run a command with this context must be over, retry delays included;
+// the attempts' timeout is shortened accordingly, and no retry happens once this time limit is reached
+func (thisCtx *baseCancelableContext) WithTimeLimit(timeLimit time.Time) CancelableContext {
+	thisCtx.timeLimit = timeLimit
+	return thisCtx
+}
+
+func (thisCtx *baseCancelableContext) getTimeLimit() time.Time {
+	return thisCtx.timeLimit
+}
+
+// allows the failed commands to be retried, up to the given number of times, waiting for the given delay - doubled for
+// each new retry - before retrying; only the failures with the given exit codes are retried, if some are given
+func (thisCtx *baseCancelableContext) WithRetries(retries int, delay time.Duration, onExitCodes ...int) CancelableContext {
+	if retries <= 0 {
+		thisCtx.retryPolicy = nil
+		return thisCtx
+	}
+
+	thisCtx.retryPolicy = &retryPolicy{
+		retries:     retries,
+		delay:       core.IfThenElse(delay > 0, delay, defaultRetryDelay),
+		onExitCodes: onExitCodes,
+	}
+
+	return thisCtx
+}
+
+func (thisCtx *baseCancelableContext) getRetryPolicy() *retryPolicy {
+	return thisCtx.retryPolicy
+}
+
+// sets what actually runs the commands run with this context, e.g. a fake runner
+func (thisCtx *baseCancelableContext) WithRunner(runner Runner) CancelableContext {
+	thisCtx.runner = runner
+	return thisCtx
+}
+
+func (thisCtx *baseCancelableContext) getRunner() Runner {
+	if thisCtx.runner == nil {
+		return defaultRunner
+	}
+
+	return thisCtx.runner
+}
+
+// registers a worker - a command, a watcher, a compose stack... - that the context should wait for when cancelled;
+// the returned function has to be called when the worker is done
+func (thisCtx *baseCancelableContext) AddWorker(name string) func() {
+	return thisCtx.workers.add(name)
+}
+
+// runs the given function in a goroutine, as a worker of this context
+func (thisCtx *baseCancelableContext) Go(name string, workerFn func()) {
+	done := thisCtx.AddWorker(name)
+	go func() {
+		defer done()
+		workerFn()
+	}()
+}
+
+func (thisCtx *baseCancelableContext) WithStopTimeout(stopTimeout time.Duration) CancelableContext {
+	thisCtx.stopTimeout = stopTimeout
+	return thisCtx
+}
+
+// waits for all the workers of this context to be done, but no longer than the stop timeout;
+// returns false - after logging the workers still running - if the timeout has fired
+func (thisCtx *baseCancelableContext) Wait() bool {
+	if stillRunning := thisCtx.workers.waitFor(thisCtx.stopTimeout); len(stillRunning) > 0 {
+		Warn("Stopped waiting after %s, with these workers still running: %s", thisCtx.stopTimeout, strings.Join(stillRunning, "; "))
+		return false
+	}
+
+	return true
+}
+
+// cancels the context, and waits for its workers to be done
 func (thisCtx *baseCancelableContext) CancelAll() {
 	if thisCtx.cancelFn != nil {
 		thisCtx.cancelFn()
+		thisCtx.Wait()
 	}
 }
 
@@ -162,35 +360,62 @@ type AldevContext interface {
 // + a cancelable context for the loop over the files watched by Aldev directly
 type aldevContext struct {
 	baseCancelableContext
-	loopCtx    *baseCancelableContext // context used for the loop run by aldev
-	exitWaitMs int                    // time waited right after cancelling the loop
-	stopFn     func()                 // funtion called when the user stops the program
-	children   []CancelableContext    // children contexts
-	mx         sync.Mutex             // mutex to protect the children contexts
+	loopCtx    *baseCancelableContext   // context used for the loop run by aldev
+	exitWaitMs int                      // time waited for the workers to be done, on top of the commands' grace period, when cancelling
+	stopFn     func()                   // funtion called when the user stops the program
+	children   []*baseCancelableContext // children contexts
+	mx         sync.Mutex               // mutex to protect the loop & children contexts
 }
 
 func (aldevCtx *aldevContext) GetLoopCtx() CancelableContext {
+	aldevCtx.mx.Lock()
+	defer aldevCtx.mx.Unlock()
+
 	return aldevCtx.loopCtx
 }
 
 func (aldevCtx *aldevContext) RestartLoop() {
-	// Cancel the current loop, and thus any function (which works with context) running inside
-	aldevCtx.loopCtx.cancelFn()
+	// Recreate the context - which is born cancelled if the whole program is stopping
+	aldevCtx.mx.Lock()
+	previousLoopCtx := aldevCtx.loopCtx
+	aldevCtx.loopCtx = aldevCtx.newSubContext()
+	if aldevCtx.Err() != nil {
+		aldevCtx.loopCtx.cancelFn()
+	}
+	aldevCtx.mx.Unlock()
 
-	// Recreate the context
-	aldevCtx.loopCtx = newBaseCancelableContext()
+	// Cancel the previous loop, and thus any function (which works with context) running inside
+	previousLoopCtx.CancelAll()
 }
 
 // method override to cancel the loop context as well
 func (aldevCtx *aldevContext) CancelAll() {
 	aldevCtx.stopFn()
-	aldevCtx.loopCtx.CancelAll()
-	for _, childCtx := range aldevCtx.children {
-		childCtx.CancelAll()
-	}
 	Info("Waiting for some cleanup...")
-	time.Sleep(time.Duration(aldevCtx.exitWaitMs) * time.Millisecond) // TODO waiting
+
+	// cancelling the loop & children contexts all at once, then waiting for their commands to exit
+	aldevCtx.mx.Lock()
+	contexts := append([]*baseCancelableContext{aldevCtx.loopCtx}, aldevCtx.children...)
+	aldevCtx.mx.Unlock()
+	for _, ctx := range contexts {
+		ctx.cancelFn()
+	}
+	for _, ctx := range contexts {
+		ctx.Wait()
+	}
+
+	// this context is cancelled last, since it's what the main function waits for before exiting
 	aldevCtx.cancelFn()
+	aldevCtx.Wait()
+}
+
+// returns a new cancelable context, inheriting the stop timeout, env vars & runner of this context
+func (aldevCtx *aldevContext) newSubContext() *baseCancelableContext {
+	subCtx := newBaseCancelableContext()
+	subCtx.stopTimeout = aldevCtx.stopTimeout
+	subCtx.envVars = maps.Clone(aldevCtx.envVars)
+	subCtx.runner = aldevCtx.runner
+	return subCtx
 }
 
 func InitAldevContext(waitTimeMs int, stopFn func()) *aldevContext {
@@ -202,10 +427,11 @@ func InitAldevContext(waitTimeMs int, stopFn func()) *aldevContext {
 	// init
 	aldevCtx := &aldevContext{
 		baseCancelableContext: *newBaseCancelableContext(),
-		loopCtx:               newBaseCancelableContext(),
 		exitWaitMs:            waitTimeMs,
 		stopFn:                stopFunction,
 	}
+	aldevCtx.stopTimeout = cmdStopGracePeriod + time.Duration(waitTimeMs)*time.Millisecond
+	aldevCtx.loopCtx = aldevCtx.newSubContext()
 
 	// Initialize a context that can be interrupted:
 
@@ -232,7 +458,7 @@ func InitAldevContext(waitTimeMs int, stopFn func()) *aldevContext {
 func (aldevCtx *aldevContext) NewChildContext() CancelableContext {
 	aldevCtx.mx.Lock()
 	defer aldevCtx.mx.Unlock()
-	childCtx := newBaseCancelableContext()
+	childCtx := aldevCtx.newSubContext()
 	aldevCtx.children = append(aldevCtx.children, childCtx)
 	return childCtx
 }
@@ -240,3 +466,71 @@ func (aldevCtx *aldevContext) NewChildContext() CancelableContext {
 func (baseCancelableContext *baseCancelableContext) NewChildContext() CancelableContext {
 	panic("No child context on children contexts allowed")
 }
+
+// ----------------------------------------------------------------------------
+// Workers
+// ----------------------------------------------------------------------------
+
+// by default, how long the workers of a cancelled context are waited for: the commands have a grace period to stop,
+// after which they're killed
+const defaultStopTimeout = cmdStopGracePeriod + time.Second
+
+// the workers - commands, watchers, compose stacks... - running within a context, to be able to wait for them
+// when cancelling it
+type workerGroup struct {
+	mx      sync.Mutex
+	workers map[int]string // the names of the running workers, by ID
+	lastID  int
+	done    chan struct{} // closed when there's no running worker
+}
+
+func newWorkerGroup() *workerGroup {
+	done := make(chan struct{})
+	close(done)
+	return &workerGroup{workers: map[int]string{}, done: done}
+}
+
+// registers a new worker, and returns the function to call when it's done
+func (group *workerGroup) add(name string) func() {
+	group.mx.Lock()
+	defer group.mx.Unlock()
+
+	if len(group.workers) == 0 {
+		group.done = make(chan struct{})
+	}
+	group.lastID++
+	id := group.lastID
+	group.workers[id] = name
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			group.mx.Lock()
+			defer group.mx.Unlock()
+			delete(group.workers, id)
+			if len(group.workers) == 0 {
+				close(group.done)
+			}
+		})
+	}
+}
+
+// waits for all the workers to be done, at most for the given duration; returns the names of the ones still running
+func (group *workerGroup) waitFor(timeout time.Duration) []string {
+	group.mx.Lock()
+	done := group.done
+	group.mx.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		group.mx.Lock()
+		defer group.mx.Unlock()
+		names := []string{}
+		for _, id := range core.GetSortedKeys(group.workers) {
+			names = append(names, group.workers[id])
+		}
+		return names
+	}
+}
@@ -0,0 +1,232 @@
+// ----------------------------------------------------------------------------
+// The code here is about the dry-run mode, where the side effects - commands,
+// file writes & removals - are printed instead of being performed
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+)
+
+var dryRun bool
+
+func SetDryRun(isDryRun bool) {
+	dryRun = isDryRun
+	if dryRun {
+		Warn("DRY-RUN mode: nothing will be written, removed or run - except the commands needed to gather information")
+	}
+}
+
+func IsDryRun() bool {
+	return dryRun
+}
+
+// logs a side effect that would have been performed, if not in dry-run mode
+func DryRun(str string, params ...any) {
+	Info("[DRY-RUN] "+str, params...)
+}
+
+// ----------------------------------------------------------------------------
+// File system operations, honouring the dry-run mode
+// ----------------------------------------------------------------------------
+
+// WriteStringToFile is core.WriteStringToFile, but only printing the changes in dry-run mode
+func WriteStringToFile(filePath string, content string, params ...any) {
+	if dryRun {
+		planFileWrite(filePath, []byte(fmt.Sprintf(content, params...)))
+		return
+	}
+
+	core.WriteStringToFile(filePath, content, params...)
+}
+
+// WriteJSONObjToFile is core.WriteJsonObjToFile, but only printing the changes in dry-run mode
+func WriteJSONObjToFile(filePath string, obj any) {
+	if dryRun {
+		jsonBytes, errMarsh := json.MarshalIndent(obj, "", "  ")
+		core.PanicMsgIfErr(errMarsh, "Could not marshal the content of file '%s'", filePath)
+		planFileWrite(filePath, jsonBytes)
+		return
+	}
+
+	core.WriteJsonObjToFile(filePath, obj)
+}
+
+// ReplaceInFile is core.ReplaceInFile, but only printing the changes in dry-run mode
+func ReplaceInFile(filePath string, replacements map[string]string) {
+	if dryRun {
+		if !core.FileExists(filePath) {
+			DryRun("Would replace %s in file '%s' - which does not exist yet", describeReplacements(replacements), filePath)
+			return
+		}
+		content := string(core.ReadFile(filePath, true))
+		for _, old := range core.GetSortedKeys(replacements) {
+			content = strings.ReplaceAll(content, old, replacements[old])
+		}
+		planFileWrite(filePath, []byte(content))
+		return
+	}
+
+	core.ReplaceInFile(filePath, replacements)
+}
+
+// ReplaceInFolder is core.ReplaceInFolder, but only printing what would be done in dry-run mode
+func ReplaceInFolder(dirPath, extension string, replacements map[string]string) {
+	if dryRun {
+		DryRun("Would replace %s in the '%s' files of folder '%s'", describeReplacements(replacements), extension, dirPath)
+		return
+	}
+
+	core.ReplaceInFolder(dirPath, extension, replacements)
+}
+
+// RemoveAll is os.RemoveAll, but only printing what would be removed in dry-run mode
+func RemoveAll(pathToRemove string) error {
+	if dryRun {
+		if _, errStat := os.Stat(pathToRemove); errStat == nil {
+			DryRun("Would remove '%s'", pathToRemove)
+		}
+		return nil
+	}
+
+	return os.RemoveAll(pathToRemove)
+}
+
+// EnsureDir is core.EnsureDir, but only printing the folder creation in dry-run mode
+func EnsureDir(pathElems ...string) string {
+	if dryRun {
+		dirPath := path.Join(pathElems...)
+		if !core.DirExists(dirPath) {
+			DryRun("Would create folder '%s'", dirPath)
+		}
+		return dirPath
+	}
+
+	return core.EnsureDir(pathElems...)
+}
+
+// EnsureNoDir is core.EnsureNoDir, but only printing the folder removal in dry-run mode
+func EnsureNoDir(pathElems ...string) {
+	if dryRun {
+		if dirPath := path.Join(pathElems...); core.DirExists(dirPath) {
+			DryRun("Would remove folder '%s'", dirPath)
+		}
+		return
+	}
+
+	core.EnsureNoDir(pathElems...)
+}
+
+// returns something like: 'old1' => 'new1', 'old2' => 'new2'
+func describeReplacements(replacements map[string]string) string {
+	described := []string{}
+	for _, old := range core.GetSortedKeys(replacements) {
+		described = append(described, fmt.Sprintf("'%s' => '%s'", old, replacements[old]))
+	}
+
+	return strings.Join(described, ", ")
+}
+
+// prints what writing the given content into the given file would change
+func planFileWrite(filePath string, newContent []byte) {
+	if !core.FileExists(filePath) {
+		DryRun("Would create file '%s' (%d lines)", filePath, len(splitLines(string(newContent))))
+		if verbose {
+			fmt.Println(string(newContent))
+		}
+		return
+	}
+
+	oldContent := string(core.ReadFile(filePath, true))
+	if oldContent == string(newContent) {
+		DryRun("Would leave file '%s' unchanged", filePath)
+		return
+	}
+
+	DryRun("Would update file '%s':\n%s", filePath, strings.Join(diffLines(oldContent, string(newContent)), "\n"))
+}
+
+// ----------------------------------------------------------------------------
+// Utils - diffing
+// ----------------------------------------------------------------------------
+
+// the number of unchanged lines shown around the changed ones
+const diffContextLines = 2
+
+// above this - number of old lines x number of new lines - the diff is not computed, to spare the memory
+const diffMaxSize = 4_000_000
+
+// returns a line-based diff between the 2 given texts, with the removed lines starting with "-", the added ones
+// with "+", and a few unchanged lines around them starting with " "
+func diffLines(oldText, newText string) []string {
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+	if len(oldLines)*len(newLines) > diffMaxSize {
+		return []string{fmt.Sprintf("  (too big to be diffed: %d lines => %d lines)", len(oldLines), len(newLines))}
+	}
+
+	// the length of the longest common subsequence from each pair of positions
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// walking through it to get all the lines, with their status
+	allLines := []string{}
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			allLines = append(allLines, "  "+oldLines[i])
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			allLines = append(allLines, "- "+oldLines[i])
+			i++
+		default:
+			allLines = append(allLines, "+ "+newLines[j])
+			j++
+		}
+	}
+
+	// only keeping the changed lines, and some context around them
+	diff := []string{}
+	lastKept := -1
+	for index, line := range allLines {
+		keep := false
+		for k := max(0, index-diffContextLines); k <= min(len(allLines)-1, index+diffContextLines) && !keep; k++ {
+			keep = !strings.HasPrefix(allLines[k], "  ")
+		}
+		if keep {
+			if lastKept >= 0 && index > lastKept+1 {
+				diff = append(diff, "  ...")
+			}
+			diff = append(diff, line)
+			lastKept = index
+		}
+	}
+
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,78 @@
+// ----------------------------------------------------------------------------
+// The code here is about environment variables: reading them from env files,
+// and using them to interpolate strings
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+)
+
+// reads an env file, made of lines like: KEY=VALUE, or export KEY="VALUE"; comments start with #
+func readEnvFile(filePath string) map[string]string {
+	file, errOpen := os.Open(filePath)
+	core.PanicMsgIfErr(errOpen, "Could not open env file '%s'", filePath)
+	defer file.Close()
+
+	envVars := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// skipping the blank lines & comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// KEY=VALUE
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		core.PanicMsgIf(!found, "Env file '%s', line %d: expected KEY=VALUE, got: %s", filePath, lineNumber, line)
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		// removing the quotes, if any
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		envVars[key] = value
+	}
+	core.PanicMsgIfErr(scanner.Err(), "Could not read env file '%s'", filePath)
+
+	return envVars
+}
+
+// matches: ${VAR}, ${VAR:-default} (default if unset or empty), ${VAR-default} (default if unset), or the $${ escape sequence
+var envVarRefRegexp = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}`)
+
+// replaces the references to variables in the given string, using the given lookup function;
+// returns the interpolated string, and the names of the variables that could not be resolved
+func interpolateEnvVars(value string, lookup func(string) (string, bool)) (string, []string) {
+	unresolved := []string{}
+
+	result := envVarRefRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		// escaped reference
+		if match == "$${" {
+			return "${"
+		}
+
+		groups := envVarRefRegexp.FindStringSubmatch(match)
+		name, operator, defaultValue := groups[1], groups[2], groups[3]
+
+		if varValue, found := lookup(name); found && (varValue != "" || operator != ":-") {
+			return varValue
+		}
+		if operator != "" {
+			return defaultValue
+		}
+
+		unresolved = append(unresolved, name)
+		return match
+	})
+
+	return result, unresolved
+}
@@ -18,11 +18,11 @@ const (
 // Doing some setup of the dev environment, like installing hooks, to unify our practices across projects
 func SetupDevEnv(ctx CancelableContext) {
 	// making sure we have a local temp folder
-	core.EnsureDir("tmp")
+	EnsureDir("tmp")
 
 	// making sure a VERSION file exists
 	if !core.FileExists(versionFilePath) {
-		core.WriteStringToFile(versionFilePath, "v0.0.0")
+		WriteStringToFile(versionFilePath, "v0.0.0")
 	}
 
 	// installing hooks
@@ -30,12 +30,12 @@ func SetupDevEnv(ctx CancelableContext) {
 		pcFile := ".git/hooks/pre-commit"
 		EnsureFileFromTemplate(pcFile, templates.GitHookPRECOMMIT, TagHOTSWAPPED)
 		if !core.IsWindows() {
-			Run("Activating the pre-commit hook", ctx, false, "chmod +x %s", pcFile)
+			RunArgs("Activating the pre-commit hook", ctx, false, "chmod", "+x", pcFile)
 		}
 		cmFile := ".git/hooks/commit-msg"
 		EnsureFileFromTemplate(cmFile, templates.GitHookCOMMITMSG)
 		if !core.IsWindows() {
-			Run("Activating the commit-msg hook", ctx, false, "chmod +x %s", cmFile)
+			RunArgs("Activating the commit-msg hook", ctx, false, "chmod", "+x", cmFile)
 		}
 	}
 
@@ -44,12 +44,12 @@ func SetupDevEnv(ctx CancelableContext) {
 		return
 	}
 	if !releasesBranchExist() {
-		Run("Creating the 'releases' branch on the remote", ctx, true, "git push origin HEAD:releases")
+		RunArgs("Creating the 'releases' branch on the remote", ctx, true, "git", "push", "origin", "HEAD:releases")
 	}
 }
 
 func releasesBranchExist() bool {
-	output := RunAndGet("Getting the list of remote branches", ".", false, "git branch --remote")
+	output := RunAndGetArgs("Getting the list of remote branches", ".", false, "git", "branch", "--remote")
 	for _, branch := range strings.Split(string(output), "\n") {
 		if strings.TrimSpace(branch) == "origin/releases" {
 			return true
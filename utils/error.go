@@ -1,22 +1,158 @@
+// ----------------------------------------------------------------------------
+// The code here is about the errors: the ones returned by the public entry
+// points of this package, and the recovery from the panics - which should only
+// be done at the CLI boundary
+// ----------------------------------------------------------------------------
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"runtime"
 	"runtime/debug"
-	"time"
+	"strings"
+	"sync"
 )
 
-const waitBeforeExit = 2 * time.Second
-
+// Recover is meant to be deferred at the CLI boundary only: it logs any panic, cancels the given context - waiting for
+// its workers to be done - and exits
 func Recover(ctx CancelableContext, msg string, params ...any) {
 	if err := recover(); err != nil {
 		Error("%v", err)
 		Info("Recovered from error (%v) while %s; will cancel the whole process now; Stack: %s", err, fmt.Sprintf(msg, params...), string(debug.Stack()))
-		time.Sleep(waitBeforeExit)
-		Debug("Waiting %s for other processes to finish, then EXITING", waitBeforeExit)
-		ctx.CancelAll()
-		time.Sleep(waitBeforeExit)
+		Debug("Waiting for the other processes to finish, then EXITING")
+		ctx.CancelAll() // this waits for the workers of the context to be done
 		os.Exit(1)
 	}
 }
+
+// ----------------------------------------------------------------------------
+// Typed errors
+// ----------------------------------------------------------------------------
+
+// OpError is returned by the public entry points of this package: it tells what was being done, and wraps the cause,
+// which can be a *ConfigError, a *CommandError, one of the ErrXXX errors, or any other error
+type OpError struct {
+	Op  string // what was being done, e.g. "reading the Aldev config '.aldev.yaml'"
+	Err error  // the cause
+}
+
+func (err *OpError) Error() string {
+	return fmt.Sprintf("error while %s: %v", err.Op, err.Err)
+}
+
+func (err *OpError) Unwrap() error {
+	return err.Err
+}
+
+// ConfigError tells what's wrong with an Aldev config file
+type ConfigError struct {
+	File   string   // the main config file
+	Issues []string // the issues found, with their positions
+}
+
+func (err *ConfigError) Error() string {
+	return fmt.Sprintf("Invalid Aldev config file '%s':\n - %s", err.File, strings.Join(err.Issues, "\n - "))
+}
+
+// CommandError tells which command failed, and how
+type CommandError struct {
+	Why      string   // why the command was run
+	Command  []string // the program, then its args
+	Dir      string   // the directory the command was run from
+	ExitCode int      // the exit code, or -1 if the command could not start, or was stopped
+	TimedOut bool     // true if the command has been stopped because it was taking too long
+	Err      error    // the error returned when running the command
+	msg      string   // the message logged for this failure
+}
+
+func (err *CommandError) Error() string {
+	return err.msg
+}
+
+func (err *CommandError) Unwrap() error {
+	return err.Err
+}
+
+// ErrReleaseNotAllowed is returned when the state of the Git repo does not allow to make a release
+var ErrReleaseNotAllowed = errors.New("cannot make a release")
+
+// ErrUnknownEnv is returned when a given environment is not one of the configured ones
+var ErrUnknownEnv = errors.New("unknown environment")
+
+// ----------------------------------------------------------------------------
+// Turning panics into errors
+// ----------------------------------------------------------------------------
+
+// to be deferred in the public entry points: turns a deliberate panic - i.e. raised by core.PanicMsg or
+// core.PanicIfErr - into an *OpError for the given operation, set into the given error; any other panic - e.g. a nil
+// dereference - is a bug, which is panicked again, after logging where it comes from
+func catchPanic(errPtr *error, op string, params ...any) {
+	if recovered := recover(); recovered != nil {
+		if !isDeliberatePanic(recovered) {
+			Error("Unexpected panic while %s: %v\n%s", fmt.Sprintf(op, params...), recovered, debug.Stack())
+			panic(recovered)
+		}
+		*errPtr = &OpError{Op: fmt.Sprintf(op, params...), Err: asError(recovered)}
+	} else if *errPtr != nil {
+		if _, alreadyWrapped := (*errPtr).(*OpError); !alreadyWrapped {
+			*errPtr = &OpError{Op: fmt.Sprintf(op, params...), Err: *errPtr}
+		}
+	}
+}
+
+// returns true if the given recovered value comes from a deliberate panic, i.e. with a message or an error, rather
+// than from a runtime error
+func isDeliberatePanic(recovered any) bool {
+	switch recovered.(type) {
+	case runtime.Error:
+		return false
+	case error, string:
+		return true
+	default:
+		return false
+	}
+}
+
+func asError(recovered any) error {
+	switch value := recovered.(type) {
+	case error:
+		return value
+	case string:
+		return errors.New(value)
+	default:
+		return fmt.Errorf("%v", value)
+	}
+}
+
+// runs functions concurrently, and collects their errors - including their panics
+type errorGroup struct {
+	wg   sync.WaitGroup
+	mx   sync.Mutex
+	errs []error
+}
+
+// runs the given function in a goroutine; any panic is turned into an error for the given operation
+func (group *errorGroup) Go(fn func() error, op string, params ...any) {
+	group.wg.Go(func() {
+		var err error
+		defer func() {
+			if err != nil {
+				group.mx.Lock()
+				defer group.mx.Unlock()
+				group.errs = append(group.errs, err)
+			}
+		}()
+		defer catchPanic(&err, op, params...)
+
+		err = fn()
+	})
+}
+
+// waits for all the functions to be done, and returns their errors, joined - or nil
+func (group *errorGroup) Wait() error {
+	group.wg.Wait()
+
+	return errors.Join(group.errs...)
+}
@@ -3,32 +3,29 @@
 // ----------------------------------------------------------------------------
 package utils
 
-import (
-	"sync"
+// DownloadExternalResources downloads the external resources, like translations, vendors, etc; the errors of all
+// the downloads are returned, joined
+func DownloadExternalResources(ctx CancelableContext, withTranslations bool) (err error) {
+	defer catchPanic(&err, "downloading the external resources")
 
-	core "github.com/aldesgroup/corego"
-)
-
-// Downloading external resources, like translations, vendors, etc
-func DownloadExternalResources(ctx CancelableContext, withTranslations bool) {
 	// making sure the cache folder exists if we need it
 	if len(Config().Vendors) > 0 {
-		core.EnsureDir(GetCacheDir())
+		EnsureDir(GetCacheDir())
 	}
 
 	// syncing
-	wg := new(sync.WaitGroup)
+	group := new(errorGroup)
 
 	// proceed to download external resources
 	if withTranslations {
-		wg.Go(func() { downloadAllTranslationsFromGoogle(ctx) })
+		group.Go(func() error { return downloadAllTranslationsFromGoogle(ctx) }, "downloading the translations")
 	}
 
 	// proceed to download external resources - which only happens for JS/TS libs
 	if IsDevNative() || IsDevWebApp() {
-		wg.Go(func() { fetchVendoredLibraries(ctx) })
+		group.Go(func() error { return fetchVendoredLibraries(ctx) }, "fetching / refreshing the vendors")
 	}
 
 	// waiting here for all the tasks to be finished
-	wg.Wait()
+	return group.Wait()
 }
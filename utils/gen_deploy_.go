@@ -7,6 +7,7 @@ package utils
 
 import (
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -63,7 +64,10 @@ func GetRemoteDeploymentGenerator() iRemoteDeploymentGenerator {
 // Creating all the files to be able to deploy locally and remotely
 // ----------------------------------------------------------------------------
 
-func GenerateDeployFiles(ctx CancelableContext) {
+// GenerateDeployFiles generates the API runtime config files, and the files needed to deploy the app, if needed
+func GenerateDeployFiles(ctx CancelableContext) (err error) {
+	defer catchPanic(&err, "generating the deploy files")
+
 	// --------------------------------------------------------------------
 	// API runtimes configuration
 	// --------------------------------------------------------------------
@@ -83,19 +87,19 @@ func GenerateDeployFiles(ctx CancelableContext) {
 
 	if Config().Deploying == nil || Config().Deploying.Dir == "" {
 		Info("No 'deploying' section in the config")
-		return
+		return nil
 	}
 	if Config().Deploying.Platform == nil {
 		Info("No deployment platform configured")
-		return
+		return nil
 	}
 	if Config().Deploying.Platform.Type == "" {
 		Info("Deployment platform type should not be empty")
-		return
+		return nil
 	}
 	if Config().Deploying.Platform.Config == nil {
 		Info("'config' tag is absent for deployment platform '%s'", Config().Deploying.Platform.Type)
-		return
+		return nil
 	}
 
 	// do we need to generate stuff?
@@ -112,16 +116,19 @@ func GenerateDeployFiles(ctx CancelableContext) {
 	generationNeeded = generationNeeded || generationOngoing
 
 	// we should regen if the config has changed - it shouldn't happen very often anyway
-	aldevConfigHasChanged := !core.FileExists(".confgen") || core.EnsureModTime(".confgen").Before(core.EnsureModTime(".aldev.yaml"))
-	if aldevConfigHasChanged {
-		Info("Regeneration will happen because '.aldev.yaml' has changed since the last generation")
+	aldevConfigHasChanged := !core.FileExists(".confgen")
+	for _, configFile := range GetConfigFiles() {
+		if !aldevConfigHasChanged && core.EnsureModTime(".confgen").Before(core.EnsureModTime(configFile)) {
+			Info("Regeneration will happen because '%s' has changed since the last generation", configFile)
+			aldevConfigHasChanged = true
+		}
 	}
 	generationNeeded = generationNeeded || aldevConfigHasChanged
 
 	// we're not generating if it's already there, or we're regenerating
 	if generationNeeded {
 		// LFG
-		core.WriteStringToFile(".confgen", "ongoing")
+		WriteStringToFile(".confgen", "ongoing")
 
 		// checking the configured remote environments for the API
 		// and the described deployed environments are the same
@@ -137,7 +144,7 @@ func GenerateDeployFiles(ctx CancelableContext) {
 		// --------------------------------------------------------------------
 
 		// what we need for local deployment
-		localDir := core.EnsureDir(Config().Deploying.Dir, "local")
+		localDir := EnsureDir(Config().Deploying.Dir, "local")
 		EnsureFileFromTemplate(path.Join(localDir, "nginx.conf"), templates.LocalNGINX)
 		generateLocalComposeFile(localDir, resolvedLocalAPIConfig)
 
@@ -161,24 +168,50 @@ func GenerateDeployFiles(ctx CancelableContext) {
 		// --------------------------------------------------------------------
 
 		// what we need for remote deployment - which depend on the targeted platform
-		GetRemoteDeploymentGenerator().generateDeployConfig(core.EnsureDir(Config().Deploying.Dir, "remote"))
+		GetRemoteDeploymentGenerator().generateDeployConfig(EnsureDir(Config().Deploying.Dir, "remote"))
 
 		// --------------------------------------------------------------------
 		// Finishing
 		// --------------------------------------------------------------------
-		core.WriteStringToFile(".confgen", "done")
+		WriteStringToFile(".confgen", "done")
 	} else {
 		Debug("Configuration generation is not required")
 	}
 
-	core.WriteStringToFile(".confgen", "true")
+	WriteStringToFile(".confgen", "true")
 
+	return nil
 }
 
 // ----------------------------------------------------------------------------
 // Utils - config items and YAML nodes
 // ----------------------------------------------------------------------------
 
+// returns the names of all the API runtime environments: "local" first, then the remote ones
+func getAPIEnvNames() []string {
+	return append([]string{"local"}, core.GetSortedKeys(Config().API.Runtimes.Remote)...)
+}
+
+// returns the API runtime config for the environment with the given name
+func getAPIRuntimeConfig(envName string) *APIRuntimeConfig {
+	if envName == "local" {
+		return Config().API.Runtimes.Local
+	}
+
+	envConfig := Config().API.Runtimes.Remote[envName]
+	core.PanicMsgIf(envConfig == nil, "No API runtime configured for environment '%s'", envName)
+	return envConfig
+}
+
+// returns the dotted path, in the config, of the API runtime config of the environment with the given name
+func getAPIRuntimeConfigPath(envName string) string {
+	if envName == "local" {
+		return "api.runtimes.local"
+	}
+
+	return joinPath("api.runtimes.remote", envName)
+}
+
 // returns the env type (as a string) from the given environment with the given name
 func getEnvTypeString(envName string, envConfig *APIRuntimeConfig, failIfEmpty bool) string {
 	baseConfig := envConfig.Base.Content
@@ -235,9 +268,36 @@ func prependValue(yamlName, value string, toNode *yaml.Node) *yaml.Node {
 	return toNode
 }
 
+// the tags that can be put on the values of node B, to tell how they should be merged on top of node A, e.g.:
+//
+//	hosts: !append [host3]  # adds items at the end of the inherited list
+//	hosts: !prepend [host0] # adds items at the start of the inherited list
+//	hosts: !delete [host1]  # removes items from the inherited list
+//	db: !delete             # removes the inherited key (a list of keys can also be given, for a mapping)
+//	db: !replace {...}      # replaces the inherited mapping instead of deep-merging it
+const (
+	mergeDirectiveAPPEND  = "!append"
+	mergeDirectivePREPEND = "!prepend"
+	mergeDirectiveDELETE  = "!delete"
+	mergeDirectiveREPLACE = "!replace"
+)
+
+var mergeDirectives = []string{mergeDirectiveAPPEND, mergeDirectivePREPEND, mergeDirectiveDELETE, mergeDirectiveREPLACE}
+
+// returns the merge directive borne by the given node, if any
+func getMergeDirective(n *yaml.Node) string {
+	if n != nil && core.InSlice(mergeDirectives, n.Tag) {
+		return n.Tag
+	}
+	return ""
+}
+
 // mergeYAMLNodes merges node B on top of node A.
 // - Mappings: keys from B override or extend A
 // - Sequences/Scalars: B fully replaces A
+// - unless B's value bears a merge directive: !append, !prepend, !delete, !replace
+// The directives that could not be applied - for lack of a value in A - are kept in the result,
+// so that they can be applied by a later merge; use clearMergeDirectives on the final result.
 // Returns a new merged node (A and B are not mutated).
 func mergeYAMLNodes(a, b *yaml.Node) *yaml.Node {
 	// Unwrap documents
@@ -253,38 +313,190 @@ func mergeYAMLNodes(a, b *yaml.Node) *yaml.Node {
 		return deepCopyNode(a)
 	}
 
-	// Only deep-merge mappings; everything else (sequences, scalars) => B wins
-	if a.Kind != yaml.MappingNode || b.Kind != yaml.MappingNode {
-		return deepCopyNode(b)
+	// Merging with the node an alias points to - without redefining its anchor
+	anchor := a.Anchor
+	if a.Kind == yaml.AliasNode && b.Kind != yaml.AliasNode {
+		for a.Kind == yaml.AliasNode {
+			a = a.Alias
+		}
+		aliased := *a
+		aliased.Anchor = ""
+		a = &aliased
 	}
 
-	// Build a key->valueNode index from A
-	aIndex := make(map[string]int) // key => index of value node in a.Content
-	for i := 0; i < len(a.Content)-1; i += 2 {
-		aIndex[a.Content[i].Value] = i + 1
+	// Applying the merge directives
+	switch getMergeDirective(b) {
+	case mergeDirectiveREPLACE:
+		return withAnchor(withoutMergeDirective(b), anchor)
+
+	case mergeDirectiveAPPEND, mergeDirectivePREPEND:
+		if a.Kind != yaml.SequenceNode || b.Kind != yaml.SequenceNode {
+			return withAnchor(withoutMergeDirective(b), anchor)
+		}
+		merged := withAnchor(deepCopyNode(a), anchor)
+		items := deepCopyNode(b).Content
+		merged.Content = core.IfThenElse(getMergeDirective(b) == mergeDirectiveAPPEND,
+			append(merged.Content, items...), append(items, merged.Content...))
+		return merged
+
+	case mergeDirectiveDELETE:
+		return withAnchor(deleteYAMLItems(a, b), anchor)
+	}
+
+	// Only deep-merge mappings; everything else (sequences, scalars) => B wins
+	if a.Kind != yaml.MappingNode || b.Kind != yaml.MappingNode {
+		return withAnchor(deepCopyNode(b), anchor)
 	}
 
 	// Start with a deep copy of A
-	merged := deepCopyNode(a)
+	merged := withAnchor(deepCopyNode(a), anchor)
 
 	// Apply keys from B
 	for i := 0; i < len(b.Content)-1; i += 2 {
 		keyNode := b.Content[i]
 		valNode := b.Content[i+1]
-		key := keyNode.Value
 
-		if aValIdx, exists := aIndex[key]; exists {
-			// Key exists in A: recurse to merge the values
-			merged.Content[aValIdx] = mergeYAMLNodes(merged.Content[aValIdx], valNode)
-		} else {
+		// Index of the key's value in the merged node, if it exists
+		mergedValIdx := -1
+		for j := 0; j < len(merged.Content)-1; j += 2 {
+			if merged.Content[j].Value == keyNode.Value {
+				mergedValIdx = j + 1
+			}
+		}
+
+		switch {
+		case mergedValIdx < 0:
 			// Key is new: append a deep copy of the key+value pair
-			merged.Content = append(merged.Content, keyNode, deepCopyNode(valNode))
+			merged.Content = append(merged.Content, deepCopyNode(keyNode), deepCopyNode(valNode))
+
+		case isDeletedKey(valNode):
+			// Key is explicitly removed
+			merged.Content = append(merged.Content[:mergedValIdx-1], merged.Content[mergedValIdx+1:]...)
+
+		default:
+			// Key exists in A: recurse to merge the values
+			merged.Content[mergedValIdx] = mergeYAMLNodes(merged.Content[mergedValIdx], valNode)
+		}
+	}
+
+	return merged
+}
+
+// removes from A the items - or the keys, for a mapping - listed in B
+func deleteYAMLItems(a, b *yaml.Node) *yaml.Node {
+	merged := deepCopyNode(a)
+
+	switch {
+	case a.Kind == yaml.SequenceNode && b.Kind == yaml.SequenceNode:
+		merged.Content = nil
+		for _, item := range a.Content {
+			if !slices.ContainsFunc(b.Content, func(toDelete *yaml.Node) bool { return equalYAMLNodes(item, toDelete) }) {
+				merged.Content = append(merged.Content, deepCopyNode(item))
+			}
 		}
+
+	case a.Kind == yaml.MappingNode && b.Kind == yaml.SequenceNode:
+		for _, toDelete := range b.Content {
+			for j := 0; j < len(merged.Content)-1; j += 2 {
+				if merged.Content[j].Value == toDelete.Value {
+					merged.Content = append(merged.Content[:j], merged.Content[j+2:]...)
+					break
+				}
+			}
+		}
+
+	default:
+		Warn("Cannot apply '%s' on line %d: a list of items or keys is expected, to delete from a list or mapping",
+			mergeDirectiveDELETE, b.Line)
 	}
 
 	return merged
 }
 
+// tells if the 2 given nodes have the same content, whatever their style, comments or position
+func equalYAMLNodes(a, b *yaml.Node) bool {
+	for a.Kind == yaml.AliasNode {
+		a = a.Alias
+	}
+	for b.Kind == yaml.AliasNode {
+		b = b.Alias
+	}
+
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !equalYAMLNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sets the given anchor on the given node - so that the aliases to the node it replaces still work - unless it has its own
+func withAnchor(n *yaml.Node, anchor string) *yaml.Node {
+	if n.Anchor == "" {
+		n.Anchor = anchor
+	}
+	if n.Kind == yaml.AliasNode && n.Anchor != "" {
+		n.Anchor = "" // an alias cannot bear an anchor
+	}
+
+	return n
+}
+
+// returns a deep copy of the given node, without its merge directive, if any
+func withoutMergeDirective(n *yaml.Node) *yaml.Node {
+	clone := deepCopyNode(n)
+	if getMergeDirective(clone) != "" {
+		switch clone.Kind {
+		case yaml.MappingNode:
+			clone.Tag = "!!map"
+		case yaml.SequenceNode:
+			clone.Tag = "!!seq"
+		default:
+			clone.Tag = "" // letting the YAML encoder / decoder guess the type
+		}
+		clone.Style &^= yaml.TaggedStyle
+	}
+
+	return clone
+}
+
+// removes, in place, the merge directives left in the given node, along with the keys to delete from nothing
+func clearMergeDirectives(n *yaml.Node) {
+	removeDeletedKeys(n)
+
+	if n.Kind != yaml.AliasNode {
+		for _, child := range n.Content {
+			clearMergeDirectives(child)
+		}
+	}
+
+	if getMergeDirective(n) != "" {
+		*n = *withoutMergeDirective(n)
+	}
+}
+
+// tells if the given value means its key should be removed
+func isDeletedKey(valNode *yaml.Node) bool {
+	return getMergeDirective(valNode) == mergeDirectiveDELETE && valNode.Kind == yaml.ScalarNode
+}
+
+// removes, in place, the keys of the given mapping node with a deletion left, i.e. with nothing to delete from
+func removeDeletedKeys(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		kept := n.Content[:0]
+		for i := 0; i < len(n.Content)-1; i += 2 {
+			if valNode := n.Content[i+1]; getMergeDirective(valNode) != mergeDirectiveDELETE {
+				kept = append(kept, n.Content[i], valNode)
+			}
+		}
+		n.Content = kept
+	}
+}
+
 // deepCopyNode recursively clones a yaml.Node.
 func deepCopyNode(n *yaml.Node) *yaml.Node {
 	if n == nil {
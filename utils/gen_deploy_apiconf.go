@@ -11,6 +11,12 @@ import (
 // Generating API config files
 // ----------------------------------------------------------------------------
 
+// the content of an API config file, for a given environment
+type apiConf struct {
+	Base   yaml.Node
+	Custom yaml.Node
+}
+
 func generateAPIConfFile(envName string, envConfig *APIRuntimeConfig, regen bool, returnResolvedConfig bool) map[string]interface{} {
 	// checking the existing config file
 	confFileName := fmt.Sprintf("%s/conf-%s.yaml", Config().API.Build.SrcDir, envName)
@@ -19,28 +25,16 @@ func generateAPIConfFile(envName string, envConfig *APIRuntimeConfig, regen bool
 		return *core.ReadFileFromYAML(confFileName, &map[string]interface{}{}, true)
 	}
 
-	// building the base config for the current environment
-	base := mergeYAMLNodes(&Config().API.Runtimes.Common.Base, &envConfig.Base)
-	base = prependValue("version", "_$_VERSION_$_", base)
-	base = prependValue("port", getEnvPortString(envConfig), base)
-	base = prependValue("appdesc", Config().AppDesc, base)
-	base = prependValue("appname", Config().AppName, base)
-
 	// making a new config by merging the env config on top of the common config
-	newConf := &struct {
-		Base   yaml.Node
-		Custom yaml.Node
-	}{
-		Base:   *base,
-		Custom: *mergeYAMLNodes(&Config().API.Runtimes.Common.Custom, &envConfig.Custom),
-	}
+	newConf := buildAPIConf(envName, envConfig, secretHandlingRESOLVE)
+	checkAPIConf(envName, newConf)
 
 	// marshalling
 	newConfBytes, errMarsh := yaml.Marshal(newConf)
 	core.PanicMsgIfErr(errMarsh, "Could not marshal merged config for env '%s'", envName)
 
 	// writing out the file
-	core.WriteStringToFile(confFileName, "# Generated by Aldev, do not edit!\n%s", string(newConfBytes))
+	WriteStringToFile(confFileName, "# Generated by Aldev, do not edit!\n%s", string(newConfBytes))
 	Debug("Just wrote file : %s", confFileName)
 
 	// not really returning stuff
@@ -55,3 +49,37 @@ func generateAPIConfFile(envName string, envConfig *APIRuntimeConfig, regen bool
 
 	return resolvedConfig
 }
+
+// builds the config for the given environment, by merging the env config on top of the common config,
+// and dealing with the secret references as required
+func buildAPIConf(envName string, envConfig *APIRuntimeConfig, handling secretHandling) *apiConf {
+	// building the base config for the current environment
+	base := mergeYAMLNodes(&Config().API.Runtimes.Common.Base, &envConfig.Base)
+	base = prependValue("version", "_$_VERSION_$_", base)
+	base = prependValue("port", getEnvPortString(envConfig), base)
+	base = prependValue("appdesc", Config().AppDesc, base)
+	base = prependValue("appname", Config().AppName, base)
+
+	// working on copies, since the nodes are modified in place from now on
+	conf := &apiConf{
+		Base:   *deepCopyNode(base),
+		Custom: *deepCopyNode(mergeYAMLNodes(&Config().API.Runtimes.Common.Custom, &envConfig.Custom)),
+	}
+
+	// the merge directives have all been applied by now
+	clearMergeDirectives(&conf.Base)
+	clearMergeDirectives(&conf.Custom)
+
+	// not showing the credentials, when masking
+	if handling == secretHandlingMASK {
+		runtimePath := getAPIRuntimeConfigPath(envName)
+		maskSensitiveValues(&conf.Base, false, joinPath(runtimePath, "base"), "api.runtimes.common.base")
+		maskSensitiveValues(&conf.Custom, false, joinPath(runtimePath, "custom"), "api.runtimes.common.custom")
+	}
+
+	// no secret should be written in clear for a remote env
+	resolveSecrets(envName, &conf.Base, handling)
+	resolveSecrets(envName, &conf.Custom, handling)
+
+	return conf
+}
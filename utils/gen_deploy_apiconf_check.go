@@ -0,0 +1,334 @@
+// ----------------------------------------------------------------------------
+// The code here is about checking the generated API config files, with a few
+// built-in rules, and against the JSON Schema the API project can provide
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// the schema declared by the API project, if any, read once
+var apiConfSchema map[string]any
+
+// returns the path to the schema of the API config files, if one is declared
+func getAPIConfSchemaPath() string {
+	if Config().API.Build.ConfSchema == "" {
+		return ""
+	}
+
+	return path.Join(Config().API.Build.SrcDir, Config().API.Build.ConfSchema)
+}
+
+// returns the schema declared by the API project, or nil
+func getAPIConfSchema() map[string]any {
+	if apiConfSchema == nil {
+		if schemaPath := getAPIConfSchemaPath(); schemaPath != "" {
+			Debug("Reading the API config schema from: %s", schemaPath)
+			apiConfSchema = map[string]any{}
+			// the schema can be written in JSON or YAML, since YAML is a superset of JSON
+			core.PanicMsgIfErr(yaml.Unmarshal(core.ReadFile(schemaPath, true), &apiConfSchema),
+				"Could not parse the API config schema '%s'", schemaPath)
+		}
+	}
+
+	return apiConfSchema
+}
+
+// checks the given API config, built for the given environment, and fails with all the issues found
+func checkAPIConf(envName string, conf *apiConf) {
+	// going through a generic structure, to have the aliases & merge keys resolved
+	confBytes, errMarsh := yaml.Marshal(conf)
+	core.PanicMsgIfErr(errMarsh, "Could not marshal the API config for env '%s'", envName)
+	var generic map[string]any
+	core.PanicMsgIfErr(yaml.Unmarshal(confBytes, &generic), "Could not unmarshal the API config for env '%s'", envName)
+
+	// the built-in rules: what's always needed by a Goald-based API
+	issues := []string{}
+	base, _ := generic["base"].(map[string]any)
+	if envType, _ := base["envtype"].(string); envType == "" {
+		issues = append(issues, "base.envtype: missing")
+	}
+	if port, _ := base["port"].(int); port <= 0 {
+		issues = append(issues, "base.port: missing, or not a positive number")
+	}
+
+	// the rules from the schema provided by the API project
+	if schema := getAPIConfSchema(); schema != nil {
+		issues = append(issues, (&apiConfSchemaChecker{root: schema}).check(generic, schema, "")...)
+	}
+
+	if len(issues) > 0 {
+		sort.Strings(issues)
+		core.PanicMsg("Invalid API config for env '%s'%s:\n  - %s", envName,
+			core.IfThenElse(getAPIConfSchemaPath() == "", "", fmt.Sprintf(" (checked against '%s')", getAPIConfSchemaPath())),
+			strings.Join(issues, "\n  - "))
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Checking against a JSON Schema
+// ----------------------------------------------------------------------------
+
+// checks values against a JSON Schema - only the most useful keywords are supported: $ref (local), type, enum, const,
+// properties, required, additionalProperties, patternProperties, items, minItems, maxItems, minimum, maximum, minLength,
+// maxLength, pattern, allOf, anyOf, oneOf
+type apiConfSchemaChecker struct {
+	root map[string]any // the root schema, to resolve the references
+}
+
+// returns the issues found when checking the given value, found at the given path, against the given schema
+func (checker *apiConfSchemaChecker) check(value any, schema map[string]any, dottedPath string) (issues []string) {
+	at := core.IfThenElse(dottedPath == "", "<root>", dottedPath)
+	addIssue := func(msg string, params ...any) {
+		issues = append(issues, at+": "+fmt.Sprintf(msg, params...))
+	}
+
+	// references to other parts of the schema
+	if ref, isRef := schema["$ref"].(string); isRef {
+		refSchema := checker.resolveRef(ref)
+		if refSchema == nil {
+			addIssue("unresolvable schema reference '%s'", ref)
+			return
+		}
+		issues = append(issues, checker.check(value, refSchema, dottedPath)...)
+	}
+
+	// type & values
+	if types := asStrings(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(typ string) bool { return hasJSONType(value, typ) }) {
+		addIssue("should be of type %s, not %s", strings.Join(types, " or "), jsonTypeOf(value))
+		return // no need to go further
+	}
+	if enum, hasEnum := schema["enum"].([]any); hasEnum && !slices.ContainsFunc(enum, func(item any) bool { return reflect.DeepEqual(item, value) }) {
+		addIssue("'%v' is not one of: %v", value, enum)
+	}
+	if constant, hasConst := schema["const"]; hasConst && !reflect.DeepEqual(constant, value) {
+		addIssue("should be '%v', not '%v'", constant, value)
+	}
+
+	// combinations of schemas
+	for _, subSchema := range asSchemas(schema["allOf"]) {
+		issues = append(issues, checker.check(value, subSchema, dottedPath)...)
+	}
+	if anyOf := asSchemas(schema["anyOf"]); len(anyOf) > 0 && checker.countMatches(value, anyOf, dottedPath) == 0 {
+		addIssue("does not match any of the allowed schemas")
+	}
+	if oneOf := asSchemas(schema["oneOf"]); len(oneOf) > 0 && checker.countMatches(value, oneOf, dottedPath) != 1 {
+		addIssue("should match exactly one of the allowed schemas")
+	}
+
+	// checks depending on the type
+	switch typedValue := value.(type) {
+	case map[string]any:
+		issues = append(issues, checker.checkObject(typedValue, schema, dottedPath)...)
+
+	case []any:
+		if minItems, ok := asNumber(schema["minItems"]); ok && float64(len(typedValue)) < minItems {
+			addIssue("should have at least %v items", minItems)
+		}
+		if maxItems, ok := asNumber(schema["maxItems"]); ok && float64(len(typedValue)) > maxItems {
+			addIssue("should have at most %v items", maxItems)
+		}
+		if itemSchema, hasItems := schema["items"].(map[string]any); hasItems {
+			for i, item := range typedValue {
+				issues = append(issues, checker.check(item, itemSchema, fmt.Sprintf("%s[%d]", dottedPath, i))...)
+			}
+		}
+
+	case string:
+		if minLength, ok := asNumber(schema["minLength"]); ok && float64(len(typedValue)) < minLength {
+			addIssue("should have at least %v characters", minLength)
+		}
+		if maxLength, ok := asNumber(schema["maxLength"]); ok && float64(len(typedValue)) > maxLength {
+			addIssue("should have at most %v characters", maxLength)
+		}
+		if pattern, hasPattern := schema["pattern"].(string); hasPattern {
+			if regex, errRegex := regexp.Compile(pattern); errRegex != nil {
+				addIssue("invalid pattern in the schema: %s", pattern)
+			} else if !regex.MatchString(typedValue) {
+				addIssue("'%s' does not match the pattern: %s", typedValue, pattern)
+			}
+		}
+
+	default:
+		if number, isNumber := asNumber(value); isNumber {
+			if minimum, ok := asNumber(schema["minimum"]); ok && number < minimum {
+				addIssue("should be >= %v, not %v", minimum, value)
+			}
+			if maximum, ok := asNumber(schema["maximum"]); ok && number > maximum {
+				addIssue("should be <= %v, not %v", maximum, value)
+			}
+		}
+	}
+
+	return
+}
+
+func (checker *apiConfSchemaChecker) checkObject(object map[string]any, schema map[string]any, dottedPath string) (issues []string) {
+	// the required keys
+	for _, key := range asStrings(schema["required"]) {
+		if _, exists := object[key]; !exists {
+			issues = append(issues, joinPath(dottedPath, key)+": missing")
+		}
+	}
+
+	// the keys, in order, so that the issues always come in the same order
+	properties, _ := schema["properties"].(map[string]any)
+	patternProperties, _ := schema["patternProperties"].(map[string]any)
+	for _, key := range core.GetSortedKeys(object) {
+		keyPath := joinPath(dottedPath, key)
+		matched := false
+
+		// the declared properties
+		if propSchema, isDeclared := properties[key].(map[string]any); isDeclared {
+			issues = append(issues, checker.check(object[key], propSchema, keyPath)...)
+			matched = true
+		}
+
+		// the properties matching a pattern
+		for pattern, propSchema := range patternProperties {
+			if regex, errRegex := regexp.Compile(pattern); errRegex == nil && regex.MatchString(key) {
+				if propSchemaMap, isMap := propSchema.(map[string]any); isMap {
+					issues = append(issues, checker.check(object[key], propSchemaMap, keyPath)...)
+				}
+				matched = true
+			}
+		}
+
+		// the other properties
+		if !matched {
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					issues = append(issues, keyPath+": unknown key")
+				}
+			case map[string]any:
+				issues = append(issues, checker.check(object[key], additional, keyPath)...)
+			}
+		}
+	}
+
+	return
+}
+
+// counts how many of the given schemas the given value matches
+func (checker *apiConfSchemaChecker) countMatches(value any, schemas []map[string]any, dottedPath string) (count int) {
+	for _, schema := range schemas {
+		if len(checker.check(value, schema, dottedPath)) == 0 {
+			count++
+		}
+	}
+
+	return
+}
+
+// resolves a local reference, e.g. "#/definitions/dbServer" or "#/$defs/dbServer"
+func (checker *apiConfSchemaChecker) resolveRef(ref string) map[string]any {
+	if !strings.HasPrefix(ref, "#") {
+		return nil // only the local references are supported
+	}
+
+	current := checker.root
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		next, isMap := current[part].(map[string]any)
+		if !isMap {
+			return nil
+		}
+		current = next
+	}
+
+	return current
+}
+
+// ----------------------------------------------------------------------------
+// Utils - JSON types
+// ----------------------------------------------------------------------------
+
+func hasJSONType(value any, typ string) bool {
+	switch typ {
+	case "integer":
+		number, isNumber := asNumber(value)
+		return isNumber && number == float64(int64(number))
+	case "number":
+		_, isNumber := asNumber(value)
+		return isNumber
+	default:
+		return jsonTypeOf(value) == typ
+	}
+}
+
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if _, isNumber := asNumber(value); isNumber {
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+func asNumber(value any) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+
+	return 0, false
+}
+
+// a JSON Schema keyword that can be a string, or a list of strings
+func asStrings(value any) (strs []string) {
+	switch typedValue := value.(type) {
+	case string:
+		return []string{typedValue}
+	case []any:
+		for _, item := range typedValue {
+			if str, isStr := item.(string); isStr {
+				strs = append(strs, str)
+			}
+		}
+	}
+
+	return
+}
+
+// a JSON Schema keyword that's a list of schemas
+func asSchemas(value any) (schemas []map[string]any) {
+	items, _ := value.([]any)
+	for _, item := range items {
+		if schema, isSchema := item.(map[string]any); isSchema {
+			schemas = append(schemas, schema)
+		}
+	}
+
+	return
+}
@@ -74,7 +74,7 @@ func (thisGen *azureDeploymentGenerator) generateInfraDeployment(remoteDir strin
 	// Starting with the GLOBAL stuff
 	global := Config().Deploying.Platform.Config.Global
 	globalDirName := "0-glo"
-	globalDir := core.EnsureDir(path.Join(remoteDir, infraDir, globalDirName))
+	globalDir := EnsureDir(path.Join(remoteDir, infraDir, globalDirName))
 
 	// Are we using Gitlab for CI/CD?
 	isGitlabCICD := Config().Deploying.CICD != nil && Config().Deploying.CICD.Type == "gitlab"
@@ -107,7 +107,7 @@ func (thisGen *azureDeploymentGenerator) generateInfraDeployment(remoteDir strin
 		// creating the folder for the current environment
 		envType := getEnvType(envName, true)
 		envDirName := fmt.Sprintf("%d-%s", envType, envName)
-		envDir := core.EnsureDir(path.Join(remoteDir, infraDir, envDirName))
+		envDir := EnsureDir(path.Join(remoteDir, infraDir, envDirName))
 
 		// creating the Terraform backend file for the current env
 		EnsureFileFromTemplate(path.Join(envDir, "backend.tf"), replaceIn(envName, envParams, azure.TerraformAzureBACKEND, "resource_ns"), envName)
@@ -141,7 +141,7 @@ func (thisGen *azureDeploymentGenerator) generateAPIMDeployment(remoteDir string
 
 	// this is the main file generically describing the infrastructure, for all the environment types
 	// so it's meant to be used by each environment with a custom config
-	core.EnsureDir(path.Join(remoteDir, apimDir))
+	EnsureDir(path.Join(remoteDir, apimDir))
 	EnsureFileFromTemplate(path.Join(remoteDir, apimDir, "main.tf"), replaceIn("global", global, azure.TerraformAzureAPIMxCOMMON, "resource_ns"))
 
 	// now dealing for each environment
@@ -149,7 +149,7 @@ func (thisGen *azureDeploymentGenerator) generateAPIMDeployment(remoteDir string
 		// creating the folder for the current environment
 		envType := getEnvType(envName, true)
 		envDirName := fmt.Sprintf("%d-%s", envType, envName)
-		envDir := core.EnsureDir(path.Join(remoteDir, apimDir, envDirName))
+		envDir := EnsureDir(path.Join(remoteDir, apimDir, envDirName))
 
 		// creating the Terraform backend file for the current env
 		EnsureFileFromTemplate(path.Join(envDir, "backend.tf"), replaceIn(envName, envParams, azure.TerraformAzureBACKEND, "resource_ns"), envName+".apim")
@@ -116,8 +116,8 @@ func getVolumesPart(dbConfigs map[string]map[string]interface{}) string {
 	if hasPostgreSQLDb(dbConfigs) {
 		// let's create a volume for the PostgreSQL database, so that the data is persisted across container restarts
 		localEnvCtx := NewBaseContext().WithStdErrWriter(os.Stdout).WithStdOutWriter(os.Stdout).WithAllowFailure(true)
-		if !Run("Checking if the 'postgres_data' volume exists", localEnvCtx, false, "%s", "podman volume exists postgres_data") {
-			Run("Creating the 'postgres_data' volume", localEnvCtx, false, "%s", "podman volume create postgres_data")
+		if !RunArgs("Checking if the 'postgres_data' volume exists", localEnvCtx, false, "podman", "volume", "exists", "postgres_data") {
+			RunArgs("Creating the 'postgres_data' volume", localEnvCtx, false, "podman", "volume", "create", "postgres_data")
 		}
 
 		// let's use it in the compose file, so that the DB data is persisted across container restarts
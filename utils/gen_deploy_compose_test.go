@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetVolumesPart(t *testing.T) {
+	postgresDb := map[string]map[string]interface{}{"main": {"type": "postgresql"}}
+
+	tests := []struct {
+		name        string
+		dbConfigs   map[string]map[string]interface{}
+		script      func(runner *FakeRunner)
+		wantCmds    []string
+		wantVolumes bool // if the compose file should declare the DB volume
+	}{
+		{
+			name:      "no PostgreSQL database",
+			dbConfigs: map[string]map[string]interface{}{"cache": {"type": "redis"}},
+			wantCmds:  []string{},
+		},
+		{
+			name:        "existing volume",
+			dbConfigs:   postgresDb,
+			wantCmds:    []string{"podman volume exists postgres_data"},
+			wantVolumes: true,
+		},
+		{
+			name:        "missing volume",
+			dbConfigs:   postgresDb,
+			script:      func(runner *FakeRunner) { runner.On("podman", "volume", "exists").ExitCode(1) },
+			wantCmds:    []string{"podman volume exists postgres_data", "podman volume create postgres_data"},
+			wantVolumes: true,
+		},
+		{
+			name:      "volume that cannot be created",
+			dbConfigs: postgresDb,
+			script: func(runner *FakeRunner) {
+				runner.On("podman", "volume", "exists").ExitCode(1)
+				runner.On("podman", "volume", "create").ExitCode(125)
+			},
+			wantCmds:    []string{"podman volume exists postgres_data", "podman volume create postgres_data"},
+			wantVolumes: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := useFakeRunner(t)
+			if test.script != nil {
+				test.script(runner)
+			}
+
+			var volumesPart string
+			if err := runCatching(func() { volumesPart = getVolumesPart(test.dbConfigs) }); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertCommandLines(t, runner, test.wantCmds...)
+			if hasVolumes := strings.Contains(volumesPart, "postgres_data:"); hasVolumes != test.wantVolumes {
+				t.Errorf("unexpected volumes part: %q", volumesPart)
+			}
+		})
+	}
+}
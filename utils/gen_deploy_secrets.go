@@ -0,0 +1,164 @@
+// ----------------------------------------------------------------------------
+// The code here is about the secrets referenced in the API runtime configs,
+// e.g. `pass: !secret db_admin_pass`, which should never be committed, or
+// land in the generated files of a remote environment
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+	"gopkg.in/yaml.v3"
+)
+
+// the YAML tag used to reference a secret
+const secretTAG = "!secret"
+
+// the value shown instead of a secret
+const secretMASK = "********"
+
+// what to do with the secrets references when building an API config
+type secretHandling int
+
+const (
+	secretHandlingRESOLVE secretHandling = iota // the actual value for the local env, an env var placeholder for the remote envs
+	secretHandlingMASK                          // a masked value, whatever the env
+)
+
+// the secrets used for the local env, read from the secrets file next to the config file
+var localSecrets map[string]string
+
+// returns the path to the secrets file for the given config file, e.g. ".aldev.secrets.yaml" for ".aldev.yaml"
+func getSecretsFilePath(cfgFileName string) string {
+	extension := path.Ext(cfgFileName)
+	return strings.TrimSuffix(cfgFileName, extension) + ".secrets" + extension
+}
+
+// returns the local secrets, reading them if needed
+func getLocalSecrets() map[string]string {
+	if localSecrets == nil {
+		localSecrets = map[string]string{}
+		if secretsFilePath := getSecretsFilePath(GetConfigFiles()[0]); core.FileExists(secretsFilePath) {
+			Debug("Reading the local secrets from: %s", secretsFilePath)
+			core.ReadFileFromYAML(secretsFilePath, &localSecrets, true)
+		}
+	}
+
+	return localSecrets
+}
+
+// replaces all the secret references found in the given node, according to the given handling & environment
+func resolveSecrets(envName string, node *yaml.Node, handling secretHandling) {
+	missing := []string{}
+	resolveSecretsRecursively(envName, node, handling, &missing)
+
+	if len(missing) > 0 {
+		core.PanicMsg("Missing secrets for env '%s': %s; they should be defined in '%s'",
+			envName, strings.Join(missing, ", "), getSecretsFilePath(GetConfigFiles()[0]))
+	}
+}
+
+func resolveSecretsRecursively(envName string, node *yaml.Node, handling secretHandling, missing *[]string) {
+	// going down the tree
+	if node.Kind != yaml.ScalarNode {
+		for _, child := range node.Content {
+			resolveSecretsRecursively(envName, child, handling, missing)
+		}
+		return
+	}
+
+	// only dealing with the secret references here
+	if node.Tag != secretTAG {
+		return
+	}
+
+	secretName := node.Value
+	node.Tag = "!!str"
+	node.Style = yaml.DoubleQuotedStyle
+
+	switch {
+	case handling == secretHandlingMASK:
+		// showing which secret is used, so that using another one shows up
+		node.Value = secretTAG + " " + secretName
+
+	case envName == "local":
+		secretValue, found := getLocalSecrets()[secretName]
+		if !found {
+			*missing = append(*missing, secretName)
+		}
+		node.Value = secretValue
+
+	default:
+		// remote envs get their secrets from their environment, at runtime
+		node.Value = "${" + secretEnvVarName(secretName) + "}"
+	}
+}
+
+// the keys whose values should not be shown, whatever they come from
+var sensitiveKeyRegexp = regexp.MustCompile(`(?i)pass|secret|token`)
+
+// masks, in the given node, the values which should not be shown: the ones coming from variables are shown as the
+// references they come from, and the ones of sensitive keys are masked; the secret references are left to resolveSecrets.
+// The given dotted paths are the ones the node's values may come from in the config, by order of precedence
+func maskSensitiveValues(node *yaml.Node, sensitive bool, configPaths ...string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			maskSensitiveValues(child, sensitive, configPaths...)
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			maskSensitiveValues(item, sensitive, joinPaths(configPaths, fmt.Sprintf("%d", i))...)
+		}
+
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			key := node.Content[i-1].Value
+			maskSensitiveValues(node.Content[i], sensitive || sensitiveKeyRegexp.MatchString(key), joinPaths(configPaths, key)...)
+		}
+
+	case yaml.ScalarNode:
+		if node.Tag == secretTAG {
+			return
+		}
+		if original := originalValue(node.Value, configPaths); original != "" {
+			node.Value, node.Tag, node.Style = original, "!!str", yaml.DoubleQuotedStyle
+		} else if sensitive && node.Value != "" && node.ShortTag() != "!!null" {
+			node.Value, node.Tag, node.Style = secretMASK, "!!str", yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+// returns the original value - with the ${VAR} references - of the given value, if it's been obtained by interpolation
+// at one of the given dotted paths
+func originalValue(value string, configPaths []string) string {
+	for _, configPath := range configPaths {
+		if interpolated := interpolatedValues[configPath]; interpolated != nil && interpolated.value == value {
+			return interpolated.original
+		}
+	}
+
+	return ""
+}
+
+// appends the given key to each of the given dotted paths
+func joinPaths(dottedPaths []string, key string) []string {
+	joined := []string{}
+	for _, dottedPath := range dottedPaths {
+		joined = append(joined, joinPath(dottedPath, key))
+	}
+
+	return joined
+}
+
+var nonEnvVarCharsRegexp = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// the name of the environment variable bearing the given secret, e.g. DB_ADMIN_PASS for "db/admin-pass"
+func secretEnvVarName(secretName string) string {
+	return nonEnvVarCharsRegexp.ReplaceAllString(strings.ToUpper(secretName), "_")
+}
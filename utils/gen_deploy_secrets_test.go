@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMaskSensitiveValues(t *testing.T) {
+	// a config where some literals are equal to interpolated values, without coming from the variables
+	t.Setenv("DB_HOST", "db.example.com")
+	t.Setenv("DB_PORT", "5432")
+	t.Setenv("DB_PASS", "s3cr3t")
+	cfgFileName := filepath.Join(t.TempDir(), ".aldev.yaml")
+	writeFile(t, cfgFileName, `version: 2
+appname: shop
+api:
+  build:
+    srcdir: api
+    bindir: bin
+  localdev:
+    instances: 1
+  doc:
+    path: api-doc.yaml
+  runtimes:
+    common:
+      base:
+        db:
+          host: ${DB_HOST}
+          port: ${DB_PORT}
+          password: ${DB_PASS}
+        cache:
+          host: db.example.com
+          port: 5432
+          token: 5432
+    local:
+      port: 8080
+      base:
+        envtype: local
+        mirror:
+          host: db.example.com
+`)
+
+	previousConfig, previousInterpolatedValues := config, interpolatedValues
+	t.Cleanup(func() { config, interpolatedValues = previousConfig, previousInterpolatedValues })
+	if errRead := ReadConfig(cfgFileName); errRead != nil {
+		t.Fatal(errRead)
+	}
+
+	maskedConfig := getMaskedConfig()
+	localAPIConf := buildAPIConf("local", getAPIRuntimeConfig("local"), secretHandlingMASK)
+
+	tests := []struct {
+		name string
+		node *yaml.Node
+		path string
+		want string
+	}{
+		{"interpolated value", maskedConfig, "api.runtimes.common.base.db.host", "${DB_HOST}"},
+		{"interpolated number", maskedConfig, "api.runtimes.common.base.db.port", "${DB_PORT}"},
+		{"interpolated sensitive value", maskedConfig, "api.runtimes.common.base.db.password", "${DB_PASS}"},
+		{"literal equal to an interpolated value", maskedConfig, "api.runtimes.common.base.cache.host", "db.example.com"},
+		{"literal number equal to an interpolated value", maskedConfig, "api.runtimes.common.base.cache.port", "5432"},
+		{"sensitive literal", maskedConfig, "api.runtimes.common.base.cache.token", secretMASK},
+		{"interpolated value in an API conf", &localAPIConf.Base, "db.host", "${DB_HOST}"},
+		{"literal in an API conf", &localAPIConf.Base, "cache.host", "db.example.com"},
+		{"env literal in an API conf", &localAPIConf.Base, "mirror.host", "db.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := test.node
+			if root.Kind == yaml.DocumentNode {
+				root = root.Content[0]
+			}
+			valueNode := getYAMLValue(root, strings.Split(test.path, ".")...)
+			if valueNode == nil {
+				t.Fatalf("no value at '%s'", test.path)
+			}
+			if valueNode.Value != test.want {
+				t.Errorf("unexpected value at '%s': got %q, want %q", test.path, valueNode.Value, test.want)
+			}
+		})
+	}
+}
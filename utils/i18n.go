@@ -9,7 +9,6 @@ import (
 	"path"
 	"slices"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/aldesgroup/aldev/templates"
@@ -28,13 +27,10 @@ type translationKey string
 type translationValue string
 
 // Downloading all the translations, i.e. all the translation files configured, for all the applications that need them
-func downloadAllTranslationsFromGoogle(ctx CancelableContext) {
-	// making sure we recover any big crashing error
-	defer Recover(ctx, "downloading the external resources")
-
+func downloadAllTranslationsFromGoogle(ctx CancelableContext) error {
 	// no translation for a library
 	if IsDevLibrary() {
-		return
+		return nil
 	}
 
 	// if there's an API - which maybe serves the translation for a web app, we need translations for it
@@ -49,6 +45,8 @@ func downloadAllTranslationsFromGoogle(ctx CancelableContext) {
 		// making the i18n files available in the native app
 		generateI18nFile(languages, namespaces)
 	}
+
+	return nil
 }
 
 // Downloading all the translations files configured for a given application (the API, or native app)
@@ -67,7 +65,7 @@ func downloadTranslationsForApp(destDir string, part string, i18nCfg *I18nConfig
 	destFolder := path.Join(destDir, i18nCfg.Folder)
 
 	// removing the old one, if it exists
-	core.EnsureNoDir(destFolder)
+	EnsureNoDir(destFolder)
 
 	// gathering all the translations in a map, allowing overrides
 	allTranslations := map[translationLanguage]map[translationNamespace]map[translationKey]translationValue{}
@@ -137,21 +135,21 @@ func downloadTranslationsForApp(destDir string, part string, i18nCfg *I18nConfig
 	}
 
 	// Now, we'll generate all the files in parallel to go fast
-	waitGroup := new(sync.WaitGroup)
+	group := new(errorGroup)
 
 	// We'll generate 1 file per language, per namespace
 	for language, translationsForLanguage := range allTranslations {
 		for namespace, translationsForNamespace := range translationsForLanguage {
-			// one more worker to wait for!
-			waitGroup.Add(1)
-
 			// here's a little worker to handler 1 couple (language x namespace)
-			go createFile(waitGroup, destFolder, language, namespace, translationsForNamespace)
+			group.Go(func() error {
+				createFile(destFolder, language, namespace, translationsForNamespace)
+				return nil
+			}, "writing the '%s' translations for '%s'", namespace, language)
 		}
 	}
 
 	// Let's wait here for everyone to be finished
-	waitGroup.Wait()
+	core.PanicIfErr(group.Wait())
 
 	// writing out to a file
 	Info("Done downloading the translations into '%s' in %s", destFolder, time.Since(start))
@@ -165,11 +163,8 @@ func downloadTranslationsForApp(destDir string, part string, i18nCfg *I18nConfig
 	return
 }
 
-func createFile(wg *sync.WaitGroup, destFolder string, lg translationLanguage,
-	ns translationNamespace, translations map[translationKey]translationValue) {
-	// whatever happens, this worker will end up being DONE
-	defer wg.Done()
-
+func createFile(destFolder string, lg translationLanguage, ns translationNamespace,
+	translations map[translationKey]translationValue) {
 	// let's get the keys, and sort them
 	keys := slices.Collect(maps.Keys(translations))
 	slices.Sort(keys)
@@ -188,7 +183,7 @@ func createFile(wg *sync.WaitGroup, destFolder string, lg translationLanguage,
 	filepath := path.Join(destFolder, string(lg), filename)
 
 	// ok for writing it all out
-	core.WriteStringToFile(filepath, "%s", contentString)
+	WriteStringToFile(filepath, "%s", contentString)
 
 	Debug("Wrote: %s", filepath)
 }
@@ -1,47 +1,172 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
-	"sync"
+	"os"
+	"strings"
 	"time"
+
+	core "github.com/aldesgroup/corego"
 )
 
-func RunJobs(aldevCtx CancelableContext, parallel bool) {
+// RunJobs runs all the configured jobs; in sequence, it stops at the first failing job, and in parallel, the errors
+// of all the failing jobs are returned, joined
+func RunJobs(aldevCtx CancelableContext, parallel bool) error {
 	if parallel {
-		runJobsInParallel(aldevCtx, Config().Jobs)
-	} else {
-		runJobsInSequence(aldevCtx, Config().Jobs)
+		return runJobsInParallel(aldevCtx, Config().Jobs)
 	}
+
+	return runJobsInSequence(aldevCtx, Config().Jobs)
 }
 
-func runJobsInParallel(aldevCtx CancelableContext, jobs []*JobConfig) {
+func runJobsInParallel(aldevCtx CancelableContext, jobs []*JobConfig) error {
 	startTime := time.Now()
-	wg := new(sync.WaitGroup)
+	group := new(errorGroup)
 	for _, job := range jobs {
-		wg.Add(1)
-		go runJob(aldevCtx, job, wg)
+		group.Go(func() error { return runJob(aldevCtx, job, true) }, "running job '%s'", job.Description) // prefixing the output lines, to tell the jobs apart
 	}
 
-	wg.Wait()
+	if errJobs := group.Wait(); errJobs != nil {
+		return errJobs
+	}
 	Debug("All jobs in parallel have been run in %dms\n", time.Since(startTime).Milliseconds())
+
+	return nil
 }
 
-func runJobsInSequence(aldevCtx CancelableContext, jobs []*JobConfig) {
+func runJobsInSequence(aldevCtx CancelableContext, jobs []*JobConfig) error {
 	startTime := time.Now()
 	for _, job := range jobs {
-		runJob(aldevCtx, job, nil)
+		if errJob := runJob(aldevCtx, job, false); errJob != nil {
+			return errJob
+		}
 		Debug("Job '%s' has been run in %dms", job.Description, time.Since(startTime).Milliseconds())
 	}
 	Debug("All jobs in sequence have been run in %dms\n", time.Since(startTime).Milliseconds())
+
+	return nil
 }
 
-func runJob(aldevCtx CancelableContext, job *JobConfig, wg *sync.WaitGroup) {
-	defer wg.Done()
-	defer Recover(aldevCtx, "Job '%s' has failed", job.Description)
+func runJob(aldevCtx CancelableContext, job *JobConfig, prefixOutput bool) (err error) {
+	defer catchPanic(&err, "running job '%s'", job.Description)
 
 	Debug("Running job '%s'", job.Description)
 
+	// the time limit for the whole job, if any
+	jobTimeout := parseDuration(job.Timeout, "job '%s' timeout", job.Description)
+	jobDeadline := time.Now().Add(jobTimeout)
+
 	for i, cmd := range job.Cmds {
-		Run(fmt.Sprintf("Command %d", i+1), aldevCtx.NewChildContext().WithExecDir(cmd.From).WithAllowFailure(cmd.FailOK), false, "%s", cmd.Exec)
+		cmdCtx := aldevCtx.NewChildContext().WithExecDir(cmd.From).WithAllowFailure(cmd.FailOK)
+		withEnv(withEnv(cmdCtx, job.EnvFile, job.Env), cmd.EnvFile, cmd.Env) // the command's env vars win over the job's ones
+		if prefixOutput {
+			cmdCtx.WithOutputPrefix(job.Description)
+		}
+
+		// the timeout & retries of the command, within the job's time limit - which the retry delays count against
+		if jobTimeout > 0 {
+			core.PanicMsgIf(time.Until(jobDeadline) <= 0, "Job '%s' timed out after %s, before command %d", job.Description, jobTimeout, i+1)
+			cmdCtx.WithTimeLimit(jobDeadline)
+		}
+		cmdCtx.WithTimeout(parseDuration(cmd.Timeout, "job '%s', command %d timeout", job.Description, i+1)).
+			WithRetries(cmd.Retries, parseDuration(cmd.RetryDelay, "job '%s', command %d retry delay", job.Description, i+1), cmd.RetryOn...)
+		switch {
+		case cmd.Script != "":
+			runScript(fmt.Sprintf("Script %d", i+1), cmdCtx, cmd.Script)
+		case cmd.Shell:
+			RunShell(fmt.Sprintf("Command %d", i+1), cmdCtx, false, cmd.Exec)
+		default:
+			RunArgs(fmt.Sprintf("Command %d", i+1), cmdCtx, false, splitCommandLine(cmd.Exec)...)
+		}
+	}
+
+	return nil
+}
+
+// runs the given script, stopping at its first failing line
+func runScript(whyRunThis string, ctx CancelableContext, script string) {
+	if !core.IsWindows() {
+		RunShell(whyRunThis, ctx, false, "set -e\n"+script)
+		return
+	}
+
+	// cmd.exe runs a single line, so the script is run from a batch file - which keeps the multi-line constructs working
+	scriptFile, errCreate := os.CreateTemp("", "aldev-script-*.cmd")
+	core.PanicMsgIfErr(errCreate, "Could not create a batch file for the script")
+	defer os.Remove(scriptFile.Name())
+	_, errWrite := scriptFile.WriteString(checkingEachStatement(script))
+	core.PanicMsgIfErr(errors.Join(errWrite, scriptFile.Close()), "Could not write the batch file '%s'", scriptFile.Name())
+
+	RunShell(whyRunThis, ctx, false, `"`+scriptFile.Name()+`"`)
+}
+
+// returns the given Windows script as the content of a batch file exiting with the error code of its first failing
+// statement; the exit code is checked after each top-level statement, i.e. not within a parenthesized block, nor
+// after a line continued with '^'
+func checkingEachStatement(script string) string {
+	const errorCheck = "if %errorlevel% neq 0 exit /b %errorlevel%"
+
+	lines := []string{"@echo off"}
+	depth := 0 // how deep the current line is within parenthesized blocks
+	for line := range strings.Lines(script) {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		lines = append(lines, line)
+
+		depth = max(0, depth+blockDepthChange(line))
+		if depth == 0 && !strings.HasSuffix(line, "^") {
+			lines = append(lines, errorCheck)
+		}
+	}
+
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// returns how many parenthesized blocks the given batch line opens - or closes, if negative - not counting the quoted
+// or escaped parentheses
+func blockDepthChange(line string) int {
+	change, quoted := 0, false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			quoted = !quoted
+		case quoted:
+		case line[i] == '^':
+			i++ // the next character is escaped
+		case line[i] == '(':
+			change++
+		case line[i] == ')':
+			change--
+		}
+	}
+
+	return change
+}
+
+// applies the given env file & vars - a nil value unsetting the variable - to the given context
+func withEnv(ctx CancelableContext, envFile string, env map[string]*string) CancelableContext {
+	ctx.WithEnvFile(envFile)
+	for _, key := range core.GetSortedKeys(env) {
+		if value := env[key]; value != nil {
+			ctx.WithEnvVars(key + "=" + *value)
+		} else {
+			ctx.WithoutEnvVars(key)
+		}
+	}
+
+	return ctx
+}
+
+// parses the given duration - e.g. "30s" or "5m" - if any, or returns 0
+func parseDuration(value string, what string, params ...any) time.Duration {
+	if value == "" {
+		return 0
 	}
+
+	duration, errParse := time.ParseDuration(value)
+	core.PanicMsgIfErr(errParse, "Invalid %s: '%s'", fmt.Sprintf(what, params...), value)
+
+	return duration
 }
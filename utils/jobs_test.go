@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckingEachStatement(t *testing.T) {
+	const check = "if %errorlevel% neq 0 exit /b %errorlevel%"
+
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "simple lines",
+			script: "go build ./...\n\ngo test ./...\n",
+			want:   []string{"go build ./...", check, "go test ./...", check},
+		},
+		{
+			name:   "parenthesized block",
+			script: "if exist dist (\n  rmdir /s /q dist\n  echo cleaned (dist)\n)\ngo build ./...",
+			want:   []string{"if exist dist (", "rmdir /s /q dist", "echo cleaned (dist)", ")", check, "go build ./...", check},
+		},
+		{
+			name:   "line continuations",
+			script: "go test ^\n  -race ^\n  ./...",
+			want:   []string{"go test ^", "-race ^", "./...", check},
+		},
+		{
+			name:   "quoted & escaped parentheses",
+			script: "echo \"(\" ^(\ngo vet ./...",
+			want:   []string{"echo \"(\" ^(", check, "go vet ./...", check},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := strings.Join(append([]string{"@echo off"}, test.want...), "\r\n") + "\r\n"
+			if got := checkingEachStatement(test.script); got != want {
+				t.Errorf("unexpected batch file:\n got: %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
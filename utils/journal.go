@@ -0,0 +1,243 @@
+// ----------------------------------------------------------------------------
+// The code here is about the journal of all the commands run by Aldev, kept
+// as JSON lines in the cache folder, to know afterwards what has been run,
+// how long it took, and why it failed
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	core "github.com/aldesgroup/corego"
+)
+
+const (
+	journalFILENAME  = "aldev-journal.jsonl"
+	journalMAXSIZE   = 5 * 1024 * 1024 // above this size, the journal is rotated, i.e. the previous one is replaced
+	stderrTAILLENGTH = 4096            // how many of the last bytes written on stderr are kept in the journal
+)
+
+// JournalEntry is what's recorded for each run of a command
+type JournalEntry struct {
+	ID         int       `json:"id,omitempty"`         // the position of the entry in the journal, starting at 1 - not recorded, but computed
+	Why        string    `json:"why"`                  // why the command was run
+	Command    []string  `json:"command"`              // the program, then its args
+	Dir        string    `json:"dir,omitempty"`        // the directory the command was run from
+	EnvKeys    []string  `json:"envKeys,omitempty"`    // the env vars set or unset specifically for the command - not their values
+	Start      time.Time `json:"start"`                // when the command started
+	End        time.Time `json:"end"`                  // when the command ended
+	DurationMs int64     `json:"durationMs"`           // how long the command lasted
+	Attempt    int       `json:"attempt,omitempty"`    // the attempt number, if the command can be retried
+	ExitCode   int       `json:"exitCode"`             // the exit code, or -1 if the command could not start, or was killed
+	Canceled   bool      `json:"canceled,omitempty"`   // true if the command was stopped because its context was cancelled
+	Error      string    `json:"error,omitempty"`      // the error, if the command failed
+	StderrTail string    `json:"stderrTail,omitempty"` // the last lines written by the command on stderr, if it failed
+}
+
+// returns true if the command went fine
+func (entry *JournalEntry) Succeeded() bool {
+	return entry.Error == ""
+}
+
+// making sure the entries written concurrently do not get mixed
+var journalMx sync.Mutex
+
+// returns the path to the journal, or "" if there's no cache folder to put it in
+func getJournalPath() string {
+	if GetCacheDir() == "" {
+		return ""
+	}
+
+	return path.Join(GetCacheDir(), journalFILENAME)
+}
+
+// adds the given command's run to the journal; this should never fail the command, so the errors are only logged
+func recordInJournal(ctx CancelableContext, invocation *Invocation, attempt int, start time.Time, errRun error, stderrTail *tailWriter) {
+	journalPath := getJournalPath()
+	if journalPath == "" || dryRun {
+		return
+	}
+
+	// building the entry
+	end := time.Now()
+	entry := &JournalEntry{
+		Why:        invocation.Why,
+		Command:    invocation.Command,
+		Dir:        invocation.Dir,
+		EnvKeys:    ctx.getEnvVarKeys(),
+		Start:      start,
+		End:        end,
+		DurationMs: end.Sub(start).Milliseconds(),
+		Attempt:    attempt,
+	}
+	if errRun != nil {
+		entry.ExitCode = -1
+		if exitCode, hasExitCode := exitCodeOf(errRun); hasExitCode {
+			entry.ExitCode = exitCode
+		}
+		entry.Canceled = ctx.Err() != nil
+		entry.Error = errRun.Error()
+		entry.StderrTail = stderrTail.String()
+	}
+
+	entryBytes, errMarsh := json.Marshal(entry)
+	if errMarsh != nil {
+		Debug("Could not record the command in the journal: %v", errMarsh)
+		return
+	}
+
+	// appending it
+	journalMx.Lock()
+	defer journalMx.Unlock()
+
+	if errAppend := appendToJournal(journalPath, append(entryBytes, '\n')); errAppend != nil {
+		Debug("Could not record the command in the journal '%s': %v", journalPath, errAppend)
+	}
+}
+
+func appendToJournal(journalPath string, line []byte) error {
+	if errDir := os.MkdirAll(path.Dir(journalPath), 0o755); errDir != nil {
+		return errDir
+	}
+
+	// not letting the journal grow forever
+	if fileInfo, errStat := os.Stat(journalPath); errStat == nil && fileInfo.Size() > journalMAXSIZE {
+		if errRename := os.Rename(journalPath, journalPath+".old"); errRename != nil {
+			return errRename
+		}
+	}
+
+	file, errOpen := os.OpenFile(journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if errOpen != nil {
+		return errOpen
+	}
+	defer file.Close()
+
+	_, errWrite := file.Write(line)
+
+	return errWrite
+}
+
+// ----------------------------------------------------------------------------
+// Reading & replaying the journal
+// ----------------------------------------------------------------------------
+
+// JournalFilter allows to select some entries of the journal
+type JournalFilter struct {
+	Last   int           // only keeps this number of the most recent entries, if > 0
+	Failed bool          // only keeps the failed runs
+	Grep   string        // only keeps the entries whose command or reason contain this
+	Since  time.Duration // only keeps the entries started less than this ago, if > 0
+}
+
+// ReadJournal returns the entries of the journal matching the given filter, the oldest first
+func ReadJournal(filter *JournalFilter) []*JournalEntry {
+	journalPath := getJournalPath()
+	if journalPath == "" || !core.FileExists(journalPath) {
+		return nil
+	}
+
+	file, errOpen := os.Open(journalPath)
+	core.PanicMsgIfErr(errOpen, "Could not open the journal '%s'", journalPath)
+	defer file.Close()
+
+	entries := []*JournalEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, journalMAXSIZE)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		entry := &JournalEntry{}
+		if errUnmarsh := json.Unmarshal(scanner.Bytes(), entry); errUnmarsh != nil {
+			Debug("Skipping the invalid line %d of the journal: %v", lineNumber, errUnmarsh)
+			continue
+		}
+		entry.ID = lineNumber
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	core.PanicMsgIfErr(scanner.Err(), "Could not read the journal '%s'", journalPath)
+
+	if filter != nil && filter.Last > 0 && len(entries) > filter.Last {
+		entries = entries[len(entries)-filter.Last:]
+	}
+
+	return entries
+}
+
+func (filter *JournalFilter) matches(entry *JournalEntry) bool {
+	if filter == nil {
+		return true
+	}
+
+	return (!filter.Failed || !entry.Succeeded()) &&
+		(filter.Since <= 0 || time.Since(entry.Start) <= filter.Since) &&
+		(filter.Grep == "" || strings.Contains(entry.Why, filter.Grep) || strings.Contains(joinShellWords(entry.Command), filter.Grep))
+}
+
+// returns a one-line description of the entry
+func (entry *JournalEntry) String() string {
+	status := core.IfThenElse(entry.Succeeded(), "OK", fmt.Sprintf("FAILED (%d)", entry.ExitCode))
+	if entry.Canceled {
+		status = "CANCELED"
+	}
+	fromDir := core.IfThenElse(entry.Dir == "", "", " [from "+entry.Dir+"]")
+
+	return fmt.Sprintf("#%d %s %-12s %8s  %s%s: %s", entry.ID, entry.Start.Format("2006-01-02 15:04:05"), status,
+		time.Duration(entry.DurationMs)*time.Millisecond, entry.Why, fromDir, joinShellWords(entry.Command))
+}
+
+// ReplayJournalEntry runs again the command of the journal entry with the given ID - with the current env vars,
+// since their values are not recorded - and returns true if it went fine; an error is returned if there's no such entry
+func ReplayJournalEntry(ctx CancelableContext, id int) (ok bool, err error) {
+	defer catchPanic(&err, "replaying the history entry #%d", id)
+
+	entries := ReadJournal(nil)
+	index := slices.IndexFunc(entries, func(entry *JournalEntry) bool { return entry.ID == id })
+	if index < 0 {
+		return false, fmt.Errorf("No entry #%d in the journal '%s'", id, getJournalPath())
+	}
+
+	entry := entries[index]
+	if len(entry.EnvKeys) > 0 {
+		Warn("The command was run with these specific env vars, whose values are not recorded: %s", strings.Join(entry.EnvKeys, ", "))
+	}
+
+	return RunArgs("Replaying: "+entry.Why, ctx.WithExecDir(entry.Dir), true, entry.Command...), nil
+}
+
+// ----------------------------------------------------------------------------
+// Utils - keeping the end of an output
+// ----------------------------------------------------------------------------
+
+// a writer only keeping the last bytes written into it
+type tailWriter struct {
+	mx   sync.Mutex
+	tail []byte
+}
+
+func (writer *tailWriter) Write(data []byte) (int, error) {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	writer.tail = append(writer.tail, data...)
+	if len(writer.tail) > stderrTAILLENGTH {
+		writer.tail = append(writer.tail[:0], writer.tail[len(writer.tail)-stderrTAILLENGTH:]...)
+	}
+
+	return len(data), nil
+}
+
+func (writer *tailWriter) String() string {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	return strings.TrimSpace(string(writer.tail))
+}
@@ -0,0 +1,125 @@
+// ----------------------------------------------------------------------------
+// The code here is about the output of the commands running concurrently:
+// each line is prefixed with the name of its command, in a stable color
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+)
+
+// if true, then the output of the commands is written as is, without prefixes nor colors - e.g. for the CI
+var plainOutput bool
+
+func SetPlainOutput(isPlain bool) {
+	plainOutput = isPlain
+}
+
+// the colors used for the prefixes: red, green, yellow, blue, magenta, cyan, and their bright versions
+var prefixColors = []int{31, 32, 33, 34, 35, 36, 91, 92, 93, 94, 95, 96}
+
+// making sure the lines written by different commands do not get mixed
+var prefixedOutputMx sync.Mutex
+
+// NewPrefixedWriter returns a writer that writes into the given one line by line - the incomplete lines being buffered -
+// each line being prefixed with the given name, colored the same way for the same name, if the output is a terminal
+func NewPrefixedWriter(name string, out io.Writer) io.Writer {
+	if plainOutput || out == io.Discard {
+		return out
+	}
+
+	prefix := "[" + name + "] "
+	if useColors(out) {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(name))
+		prefix = fmt.Sprintf("\033[%dm%s\033[0m", prefixColors[hash.Sum32()%uint32(len(prefixColors))], prefix)
+	}
+
+	return &prefixedWriter{out: out, prefix: []byte(prefix)}
+}
+
+type prefixedWriter struct {
+	mx      sync.Mutex
+	out     io.Writer
+	prefix  []byte
+	partial []byte // the last line written, until it's complete
+}
+
+func (writer *prefixedWriter) Write(data []byte) (int, error) {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	writer.partial = append(writer.partial, data...)
+
+	// writing all the complete lines
+	if lastEOL := bytes.LastIndexByte(writer.partial, '\n'); lastEOL >= 0 {
+		if errWrite := writer.writeLines(writer.partial[:lastEOL+1]); errWrite != nil {
+			return 0, errWrite
+		}
+		writer.partial = append(writer.partial[:0], writer.partial[lastEOL+1:]...)
+	}
+
+	return len(data), nil
+}
+
+// writes the incomplete line, if any - to be called once the command is done
+func (writer *prefixedWriter) flush() {
+	writer.mx.Lock()
+	defer writer.mx.Unlock()
+
+	if len(writer.partial) > 0 {
+		_ = writer.writeLines(append(writer.partial, '\n'))
+		writer.partial = writer.partial[:0]
+	}
+}
+
+func (writer *prefixedWriter) writeLines(lines []byte) error {
+	prefixed := new(bytes.Buffer)
+	for line := range bytes.Lines(lines) {
+		prefixed.Write(writer.prefix)
+		prefixed.Write(line)
+	}
+
+	prefixedOutputMx.Lock()
+	defer prefixedOutputMx.Unlock()
+	_, errWrite := writer.out.Write(prefixed.Bytes())
+
+	return errWrite
+}
+
+// returns a writer of its own for a command writing into the given writer, if it's a prefixed one - so that the
+// incomplete lines of the commands sharing the prefix are neither mixed nor flushed together - else the writer itself
+func commandOutput(writer io.Writer) io.Writer {
+	if prefixed, isPrefixed := writer.(*prefixedWriter); isPrefixed {
+		return &prefixedWriter{out: prefixed.out, prefix: prefixed.prefix}
+	}
+
+	return writer
+}
+
+// flushes the given writer, if it's a prefixed one
+func flushOutput(writer io.Writer) {
+	if prefixed, isPrefixed := writer.(*prefixedWriter); isPrefixed {
+		prefixed.flush()
+	}
+}
+
+// colors are only used when writing to a terminal, and if not disabled with the NO_COLOR env var
+func useColors(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	file, isFile := out.(*os.File)
+	if !isFile {
+		return false
+	}
+	fileInfo, errStat := file.Stat()
+
+	return errStat == nil && fileInfo.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCommandOutput(t *testing.T) {
+	shared := new(bytes.Buffer)
+	prefixed := NewPrefixedWriter("web", shared)
+
+	// 2 commands writing into the same prefixed output, the 1st one ending while the 2nd one's line is incomplete
+	first, second := commandOutput(prefixed), commandOutput(prefixed)
+	_, _ = second.Write([]byte("compiling... "))
+	_, _ = first.Write([]byte("done"))
+	flushOutput(first)
+	_, _ = second.Write([]byte("ok\n"))
+	flushOutput(second)
+
+	if want := "[web] done\n[web] compiling... ok\n"; shared.String() != want {
+		t.Errorf("unexpected output: got %q, want %q", shared.String(), want)
+	}
+}
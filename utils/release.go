@@ -34,51 +34,49 @@ func askConfirm(prompt string) bool {
 	return strings.EqualFold(strings.TrimSpace(line), "y")
 }
 
-func runGitCheckCmd(whyRunThis string, commandAsString string) string {
-	return strings.TrimSpace(string(RunAndGet(whyRunThis, ".", false, "%s", commandAsString)))
+func runGitCheckCmd(whyRunThis string, command ...string) string {
+	return strings.TrimSpace(string(RunAndGetArgs(whyRunThis, ".", false, command...)))
 }
 
-func MakeRelease(ctx CancelableContext, release ReleaseType) {
+// MakeRelease tags & pushes the next version of the app; an error wrapping ErrReleaseNotAllowed is returned if the
+// state of the Git repo does not allow it, and nil if the user cancels the release
+func MakeRelease(ctx CancelableContext, release ReleaseType) (err error) {
+	defer catchPanic(&err, "making a release")
+
 	// can't do this from any other branch than the main branch
-	if currentBranch := runGitCheckCmd("Getting the current Git branch", "git branch --show-current"); currentBranch != "main" {
-		Error("Can only make a release from the 'main' branch (not this '%s' branch)", currentBranch)
-		return
+	if currentBranch := runGitCheckCmd("Getting the current Git branch", "git", "branch", "--show-current"); currentBranch != "main" {
+		return fmt.Errorf("%w: Can only make a release from the 'main' branch (not this '%s' branch)", ErrReleaseNotAllowed, currentBranch)
 	}
 
 	// checking there are no uncommited changes
-	if uncommitedChanges := runGitCheckCmd("Checking for uncommited changes", "git status --porcelain"); uncommitedChanges != "" {
-		Error("There are uncommited changes, please commit or stash them before making a release")
-		return
+	if uncommitedChanges := runGitCheckCmd("Checking for uncommited changes", "git", "status", "--porcelain"); uncommitedChanges != "" {
+		return fmt.Errorf("%w: There are uncommited changes, please commit or stash them before making a release", ErrReleaseNotAllowed)
 	}
 
 	// refreshing the remote branches and tags
-	if fetchOK := QuickRun("Fetching the remote branches and tags", "git fetch --prune --prune-tags"); !fetchOK {
+	if fetchOK := QuickRunArgs("Fetching the remote branches and tags", "git", "fetch", "--prune", "--prune-tags"); !fetchOK {
 		core.PanicMsg("Could not fetch the remote branches and tags")
 	}
 
 	// checking there are no unpushed commits
-	if unpushedCommits := runGitCheckCmd("Checking for unpushed commits", "git log origin/main..HEAD --oneline"); unpushedCommits != "" {
-		Error("There are unpushed commits, please push them before making a release")
-		return
+	if unpushedCommits := runGitCheckCmd("Checking for unpushed commits", "git", "log", "origin/main..HEAD", "--oneline"); unpushedCommits != "" {
+		return fmt.Errorf("%w: There are unpushed commits, please push them before making a release", ErrReleaseNotAllowed)
 	}
 
 	// checking there are no unpushed tags
-	if unpushedTags := runGitCheckCmd("Checking for unpushed tags", "git push origin --tags --dry-run"); unpushedTags != "" {
-		Error("There are unpushed tags, please push them before making a release")
-		return
+	if unpushedTags := runGitCheckCmd("Checking for unpushed tags", "git", "push", "origin", "--tags", "--dry-run"); unpushedTags != "" {
+		return fmt.Errorf("%w: There are unpushed tags, please push them before making a release", ErrReleaseNotAllowed)
 	}
 
 	// checking there are no unpulled commits
-	if unpulledCommits := runGitCheckCmd("Checking for unpulled commits", "git log HEAD..origin/main --oneline"); unpulledCommits != "" {
-		Error("There are unpulled commits, please pull them before making a release")
-		return
+	if unpulledCommits := runGitCheckCmd("Checking for unpulled commits", "git", "log", "HEAD..origin/main", "--oneline"); unpulledCommits != "" {
+		return fmt.Errorf("%w: There are unpulled commits, please pull them before making a release", ErrReleaseNotAllowed)
 	}
 
 	// checking origin/releases hasn't diverged
-	if divergedCommits := runGitCheckCmd("Checking 'main' and 'releases' haven't diverged", "git log origin/main..origin/releases --oneline"); divergedCommits != "" {
-		Error("There are commits in the 'releases' branch that are not in the 'main' branch: \n\n%s\n\n"+
-			"Please update the 'main' branch with these commits before making a release", divergedCommits)
-		return
+	if divergedCommits := runGitCheckCmd("Checking 'main' and 'releases' haven't diverged", "git", "log", "origin/main..origin/releases", "--oneline"); divergedCommits != "" {
+		return fmt.Errorf("%w: There are commits in the 'releases' branch that are not in the 'main' branch: \n\n%s\n\n"+
+			"Please update the 'main' branch with these commits before making a release", ErrReleaseNotAllowed, divergedCommits)
 	}
 
 	// retrieving the current version from the VERSION file
@@ -96,65 +94,66 @@ func MakeRelease(ctx CancelableContext, release ReleaseType) {
 	}
 
 	// getting the current version
-	currentVersionFromGit := strings.TrimSpace(string(RunAndGet("Getting the current version", ".", false, "svu current")))
+	currentVersionFromGit := strings.TrimSpace(string(RunAndGetArgs("Getting the current version", ".", false, "svu", "current")))
 
 	// a bit of a sanity check to make sure the VERSION file is in sync with the git tags
 	if currentVersionFromFile != "" && currentVersionFromFile != currentVersionFromGit {
-		Error("VERSION file (%s) is not in sync with git tags (%s), this should never happen!", currentVersionFromFile, currentVersionFromGit)
-		return
+		return fmt.Errorf("%w: VERSION file (%s) is not in sync with git tags (%s), this should never happen!", ErrReleaseNotAllowed, currentVersionFromFile, currentVersionFromGit)
 	}
 
 	// computing the next desired version
-	nextVersion := strings.TrimSpace(string(RunAndGet("Getting the next version", ".", false, "svu %s", releaseStr)))
+	nextVersion := strings.TrimSpace(string(RunAndGetArgs("Getting the next version", ".", false, "svu", releaseStr)))
 
 	// asking for confirmation before making the release
 	if !askConfirm(fmt.Sprintf("Do you want to go from %s to %s?", currentVersionFromGit, nextVersion)) {
 		println("\nRelease cancelled")
-		return
+		return nil
 	}
 
 	// new Git tag
-	if newTagOK := QuickRun("New git tag", "git tag %s", nextVersion); !newTagOK {
+	if newTagOK := QuickRunArgs("New git tag", "git", "tag", nextVersion); !newTagOK {
 		core.PanicMsg("Weird... Somehow we failed to create new Git tag '%s'", nextVersion)
 	}
 
 	// updating (maybe creating) the VERSION file
-	core.WriteStringToFile(versionFilePath, "%s", nextVersion)
+	WriteStringToFile(versionFilePath, "%s", nextVersion)
 
 	// updating the API doc
 	if Config().API != nil && Config().API.Doc.Path != "" {
-		core.ReplaceInFile(Config().API.Doc.Path, map[string]string{"v0.0.1": nextVersion})
+		ReplaceInFile(Config().API.Doc.Path, map[string]string{"v0.0.1": nextVersion})
 		// core.ReplaceInFile(Config().API.Doc.Report, map[string]string{"v0.0.1": nextVersion})
 	}
 
 	// git-adding the VERSION file
 	if Config().API != nil && Config().API.Doc.Path != "" {
 		// if addOK := QuickRun("Adding the updated VERSION file to Git + API docs", "git add %s %s %s", versionFilePath, Config().API.Doc.Path, Config().API.Doc.Report); !addOK {
-		if addOK := QuickRun("Adding the updated VERSION file to Git + API docs", "git add %s %s", versionFilePath, Config().API.Doc.Path); !addOK {
+		if addOK := QuickRunArgs("Adding the updated VERSION file to Git + API docs", "git", "add", versionFilePath, Config().API.Doc.Path); !addOK {
 			core.PanicMsg("Could not add the updated VERSION file + API docs to Git")
 		}
-	} else if addOK := QuickRun("Adding the updated VERSION file to Git + API docs", "git add %s", versionFilePath); !addOK {
+	} else if addOK := QuickRunArgs("Adding the updated VERSION file to Git + API docs", "git", "add", versionFilePath); !addOK {
 		core.PanicMsg("Could not add the updated VERSION file + API docs to Git")
 
 	}
 
 	// commiting the updated VERSION file
-	if commitOK := QuickRun("Committing the updated VERSION file + API docs", "git commit -m \"dev: bumped version %s to %s\"", currentVersionFromGit, nextVersion); !commitOK {
+	if commitOK := QuickRunArgs("Committing the updated VERSION file + API docs", "git", "commit", "-m", fmt.Sprintf("dev: bumped version %s to %s", currentVersionFromGit, nextVersion)); !commitOK {
 		core.PanicMsg("Could not commit the updated VERSION file + API docs to Git")
 	}
 
 	// pushing to the main branch
-	if pushOK := QuickRun("Pushing to the remote 'main' branch", "git push"); !pushOK {
+	if pushOK := QuickRunArgs("Pushing to the remote 'main' branch", "git", "push"); !pushOK {
 		core.PanicMsg("Could not push to the remote 'main' branch")
 	}
 
 	// pushing the new tag
-	if pushOK := QuickRun("Pushing the new tag", "git push --tags"); !pushOK {
+	if pushOK := QuickRunArgs("Pushing the new tag", "git", "push", "--tags"); !pushOK {
 		core.PanicMsg("Could not push the new tag '%s'", nextVersion)
 	}
 
 	// pushing to the releases branch
-	if pushOK := QuickRun("Pushing to the remote 'releases' branch", "git push origin HEAD:releases"); !pushOK {
+	if pushOK := QuickRunArgs("Pushing to the remote 'releases' branch", "git", "push", "origin", "HEAD:releases"); !pushOK {
 		core.PanicMsg("Could not push to the remote 'releases' branch")
 	}
+
+	return nil
 }
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMakeReleaseChecks(t *testing.T) {
+	// the commands run to check the state of the Git repo, in order
+	const (
+		branchCmd   = "git branch --show-current"
+		statusCmd   = "git status --porcelain"
+		fetchCmd    = "git fetch --prune --prune-tags"
+		unpushedCmd = "git log origin/main..HEAD --oneline"
+		tagsCmd     = "git push origin --tags --dry-run"
+		unpulledCmd = "git log HEAD..origin/main --oneline"
+		divergedCmd = "git log origin/main..origin/releases --oneline"
+		svuCmd      = "svu current"
+	)
+
+	tests := []struct {
+		name        string
+		script      func(runner *FakeRunner)
+		versionFile string
+		wantCmds    []string
+		wantErrMsg  string
+		notAllowed  bool // if the error should be an ErrReleaseNotAllowed
+	}{
+		{
+			name:       "not on the main branch",
+			script:     func(runner *FakeRunner) { runner.On("git", "branch").Output("feature/login\n") },
+			wantCmds:   []string{branchCmd},
+			wantErrMsg: "not this 'feature/login' branch",
+			notAllowed: true,
+		},
+		{
+			name: "uncommitted changes",
+			script: func(runner *FakeRunner) {
+				runner.On("git", "status").Output(" M utils/release.go\n")
+			},
+			wantCmds:   []string{branchCmd, statusCmd},
+			wantErrMsg: "uncommited changes",
+			notAllowed: true,
+		},
+		{
+			name:       "failed fetch",
+			script:     func(runner *FakeRunner) { runner.On("git", "fetch").ExitCode(128) },
+			wantCmds:   []string{branchCmd, statusCmd, fetchCmd},
+			wantErrMsg: "git fetch --prune --prune-tags",
+		},
+		{
+			name:       "unpushed commits",
+			script:     func(runner *FakeRunner) { runner.On("git", "log", "origin/main..HEAD").Output("abc1234 fix: typo\n") },
+			wantCmds:   []string{branchCmd, statusCmd, fetchCmd, unpushedCmd},
+			wantErrMsg: "unpushed commits",
+			notAllowed: true,
+		},
+		{
+			name: "unpushed tags",
+			script: func(runner *FakeRunner) {
+				runner.On("git", "push", "origin", "--tags").Output(" * [new tag] v1.2.4 -> v1.2.4\n")
+			},
+			wantCmds:   []string{branchCmd, statusCmd, fetchCmd, unpushedCmd, tagsCmd},
+			wantErrMsg: "unpushed tags",
+			notAllowed: true,
+		},
+		{
+			name:       "unpulled commits",
+			script:     func(runner *FakeRunner) { runner.On("git", "log", "HEAD..origin/main").Output("def5678 feat: login\n") },
+			wantCmds:   []string{branchCmd, statusCmd, fetchCmd, unpushedCmd, tagsCmd, unpulledCmd},
+			wantErrMsg: "unpulled commits",
+			notAllowed: true,
+		},
+		{
+			name: "diverged releases branch",
+			script: func(runner *FakeRunner) {
+				runner.On("git", "log", "origin/main..origin/releases").Output("0123abc hotfix\n")
+			},
+			wantCmds:   []string{branchCmd, statusCmd, fetchCmd, unpushedCmd, tagsCmd, unpulledCmd, divergedCmd},
+			wantErrMsg: "0123abc hotfix",
+			notAllowed: true,
+		},
+		{
+			name:        "VERSION file out of sync with the tags",
+			script:      func(runner *FakeRunner) { runner.On("svu", "current").Output("v1.3.0\n") },
+			versionFile: "v1.2.3",
+			wantCmds:    []string{branchCmd, statusCmd, fetchCmd, unpushedCmd, tagsCmd, unpulledCmd, divergedCmd, svuCmd},
+			wantErrMsg:  "VERSION file (v1.2.3) is not in sync with git tags (v1.3.0)",
+			notAllowed:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if test.versionFile != "" {
+				if errWrite := os.WriteFile(versionFilePath, []byte(test.versionFile), 0o644); errWrite != nil {
+					t.Fatal(errWrite)
+				}
+			}
+
+			// a clean & up-to-date repo on the main branch - the commands having no output - unless stated otherwise
+			runner := useFakeRunner(t)
+			test.script(runner)
+			runner.On("git", "branch", "--show-current").Output("main\n")
+
+			err := MakeRelease(NewBaseContext(), ReleasePatch)
+
+			assertCommandLines(t, runner, test.wantCmds...)
+			if err == nil {
+				t.Fatalf("expected an error containing %q", test.wantErrMsg)
+			}
+			if !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("unexpected error: got %q, want it to contain %q", err.Error(), test.wantErrMsg)
+			}
+			if errors.Is(err, ErrReleaseNotAllowed) != test.notAllowed {
+				t.Errorf("unexpected error kind: got %v, want ErrReleaseNotAllowed: %t", err, test.notAllowed)
+			}
+		})
+	}
+}
@@ -4,152 +4,143 @@
 package utils
 
 import (
+	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	core "github.com/aldesgroup/corego"
-	"github.com/fsnotify/fsnotify"
-	"github.com/patrickmn/go-cache"
 )
 
-type exclusionType int
-
-const exclusionTypeEXCLUDExONE = 1
-const exclusionTypeEXCLUDExALL = 2
-
-var excludedPaths map[string]exclusionType
-
 // this function allows to us to continuously develop our Go source, weither it's for an API, or a library
 // this means : rebuilding it every time it's changed, and also running the needed codegen
 func RunGoSrcDev(ctx CancelableContext, noServe bool) {
 	// making sure the local env is ready for running the Go app
 	ensureLocalEnvReady()
 
-	// the paths we don't want to we watched
-	excludedPaths = map[string]exclusionType{
-		GetGoSrcDir(): exclusionTypeEXCLUDExONE, // not including the API folder itself, because of the conf file and go.sum
-		"_include":    exclusionTypeEXCLUDExALL, // obviously not trigering codegen / rebuild on codegen'd files, otherwise: infinite loop
-		"class":       exclusionTypeEXCLUDExALL, // obviously not trigering codegen / rebuild on other codegen'd files, otherwise: infinite loop
-		".git":        exclusionTypeEXCLUDExALL, // not looking into a .git folder
-		"bin":         exclusionTypeEXCLUDExALL, // also obviously not trigering on the binaries
-	}
+	// the paths we don't want to be watched, on top of the configured ones & the ones ignored by Git
+	rules := newWatchRules(GetGoWatchConfig(),
+		// not looking into a .git folder
+		"**/.git",
+		// obviously not trigering codegen / rebuild on codegen'd files, otherwise: infinite loop
+		"/"+path.Join(GetGoSrcDir(), "_include"),
+		// obviously not trigering codegen / rebuild on other codegen'd files, otherwise: infinite loop
+		"**/class",
+		// also obviously not trigering on the binaries
+		"/"+Config().ResolvedBinDir(),
+	)
+
+	// the files right in the source folder - like the conf file and go.sum - are not watched, unless explicitly included
+	includingFiles := GetGoWatchConfig() != nil && len(GetGoWatchConfig().Include) > 0
 
 	// the root paths to watch for changes
 	rootPaths := append(GetGoAdditionalWatchedPaths(), GetGoSrcDir())
-
-	// which files are going to be impacted?
-	watchedFolders := getWatchedFolders(rootPaths...)
+	Debug("Watching %s for changes, %s", strings.Join(rootPaths, ", "), rules)
 
 	// performing the initial build & run
-	go devUp(noServe)
+	ctx.Go("building & starting the API", func() { devUp(ctx, noServe) })
+
+	// rebuilding & reloading the API whenever the source files change
+	watchingDone := NewWatcher("watching the Go source files", func(events []*WatchEvent) {
+		Debug("/!\\ Files changed: %s", DescribeWatchEvents(events))
 
-	// adding a watcher to detect some file changes, for additional needed swaps
-	watcher := WatcherFor(watchedFolders...)
+		// reloading only what's needed, without holding the next changes back
+		requestDevReload(ctx, noServe)
+	}, rootPaths...).WithSkippedDirs(rules.skipsDir).WithTreeRefresh(rules.changedBy).WithEventFilter(func(event *WatchEvent) bool {
+		return (includingFiles || filepath.Dir(event.Path) != filepath.Clean(GetGoSrcDir())) && rules.keeps(event)
+	}).Start(ctx)
 
 	// making sure we'll roll the changes back at the end
 	defer func() {
-		// let's stop the watching right away
-		core.PanicIfErr(watcher.Close())
-
-		// TODO like in Aldev, wait for this to be done instead of sleeping
-		time.Sleep(10 * time.Millisecond)
+		// waiting for the watching to be stopped
+		<-watchingDone
 
 		// removing the currently running stuff
 		devDown()
 	}()
 
-	// watching all the files here and rebooting the watching if something is changed
-	// so as to handle new files, or new imports in existing files for instance
-	go func() {
-		// capturing ghost changes on files
-		cache := cache.New(3*time.Second, time.Minute)
-
-		// listening for events
-		for {
-			select {
-			case event := <-watcher.Events:
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					if _, alreadyCaptured := cache.Get(event.String()); !alreadyCaptured {
-						// let's wait a bit first
-						time.Sleep(100 * time.Millisecond)
-
-						Debug("/!\\ File modified: %s (event = %s)", event.Name, event.String())
+	// not quitting while the context is still going
+	<-ctx.Done()
+}
 
-						// caching to prevent stuttering
-						cache.SetDefault(event.String(), true)
+// the state of the local API stack, so that the reloads do not get in the way of its start
+var (
+	devStackMx      sync.Mutex // held while building & starting the stack, or while reloading it
+	devStackServing bool       // true while 'podman-compose up' runs, even if the API replicas are not running yet
+)
 
-						// which files are going to be impacted NOW?
-						watchedFolders = getWatchedFolders(rootPaths...)
+func devUp(ctx CancelableContext, noServe bool) {
+	// the reloads have to wait for the API to be built, and its start to be initiated
+	devStackMx.Lock()
+	serving := devBuild() && IsDevAPI() && !noServe && hasLocalDeployment()
+	devStackServing = devStackServing || serving
+	devStackMx.Unlock()
 
-						// adding a watcher to detect some file changes
-						core.PanicIfErr(watcher.Close()) // closing the old one
-						watcher = WatcherFor(watchedFolders...)
+	if serving {
+		devServe(ctx)
+	}
+}
 
-						// let's wait a bit here that the FS has finished doing it's stuff
-						time.Sleep(200 * time.Millisecond)
+// only 1 build at a time
+var devBuildMx sync.Mutex
 
-						// restarting the API
-						devDown()
-						go devUp(noServe)
-					}
-				}
+// building the API and code-generating the missing stuff; returns true if it went fine
+func devBuild() bool {
+	devBuildMx.Lock()
+	defer devBuildMx.Unlock()
 
-			case errWatcher := <-watcher.Errors:
-				core.PanicIfErr(errWatcher)
-			}
-		}
-	}()
+	codeGenCtx := NewBaseContext().WithStdErrWriter(os.Stdout).WithStdOutWriter(os.Stdout).WithAllowFailure(true).WithOutputPrefix("codegen")
+	codegenCmd := []string{"aldev", "codegen"}
+	if verbose {
+		codegenCmd = append(codegenCmd, "-v")
+	}
+	if regen {
+		codegenCmd = append(codegenCmd, "-r")
+	}
 
-	// not quitting while the context is still going
-	<-ctx.Done()
+	return RunArgs("Building & code-generating", codeGenCtx, false, codegenCmd...)
 }
 
-// getting all the folders and files to watch for the API
-func getWatchedFolders(givenPaths ...string) (watchedFolders []string) {
-	// iterating over all the given folders
-	for _, givenPath := range givenPaths {
-		// add this folder to watch it,
-		if core.DirExists(givenPath) {
-			// but only if it's not completely excluded
-			if exclType := excludedPaths[path.Base(givenPath)]; exclType != exclusionTypeEXCLUDExALL {
-				// adding the current path, only if it's not excluded
-				if exclType != exclusionTypeEXCLUDExONE {
-					watchedFolders = append(watchedFolders, givenPath)
-				}
-
-				// adding subfolders
-				for _, entry := range core.EnsureReadDir(givenPath) {
-					watchedFolders = append(watchedFolders, getWatchedFolders(path.Join(givenPath, entry.Name()))...)
-				}
-			}
-		}
-	}
+// makes the given context show what the locally deployed containers output
+func withComposeOutput(ctx CancelableContext) CancelableContext {
+	return ctx.WithStdErrWriter(core.IfThenElse[io.Writer](verbose, os.Stdout, io.Discard)).WithOutputPrefix("compose")
+}
 
-	return
+func hasLocalDeployment() bool {
+	return Config().Deploying != nil && Config().Deploying.Dir != ""
 }
 
-func devUp(noServe bool) {
-	// building the API and code-generating the missing stuff
-	codeGenCtx := NewBaseContext().WithStdErrWriter(os.Stdout).WithStdOutWriter(os.Stdout).WithAllowFailure(true)
-	options := core.IfThenElse(verbose, "-v", "")
-	options += core.IfThenElse(regen, " -r", "")
-	if Run("Building & code-generating", codeGenCtx, false, "aldev codegen %s", options) && IsDevAPI() && !noServe {
-
-		// locally deploying the API with 3 instances
-		if Config().Deploying != nil && Config().Deploying.Dir != "" {
-			QuickRun("Starting the API", "podman-compose -f %s/local/compose.yaml up --scale %s_api=%d",
-				Config().Deploying.Dir, Config().AppNameShort, Config().API.LocalDev.Instances)
-		}
-	}
+// locally deploying the API with the configured number of instances, behind the load balancer, until the given context
+// is done; devStackServing should have been set beforehand
+func devServe(ctx CancelableContext) {
+	defer func() {
+		devStackMx.Lock()
+		devStackServing = false
+		devStackMx.Unlock()
+	}()
+
+	composeCtx, release := newBoundContext(ctx)
+	defer release()
+	RunArgs("Starting the API", withComposeOutput(composeCtx), verbose, "podman-compose", "-f", path.Join(Config().Deploying.Dir, "local", "compose.yaml"), "up",
+		"--scale", fmt.Sprintf("%s_api=%d", Config().AppNameShort, Config().API.LocalDev.Instances))
 }
 
 func devDown() {
+	// the restarted replicas are going away
+	stopFollowingReplicaLogs()
+
 	// Nuking everything launched with Podman... That may be a little bit too much
 	// We'll prolly have to smooth that out sometimes later
 	if IsDevAPI() {
-		QuickRun("Stopping the load balancer first", "podman rm --force --filter name=local_%s_api_lb", Config().AppNameShort)
-		QuickRun("Stopping the API instances", "podman rm --force --filter name=local_%s_api", Config().AppNameShort)
+		QuickRunArgs("Stopping the load balancer first", "podman", "rm", "--force", "--filter", "name=local_"+Config().AppNameShort+"_api_lb")
+		QuickRunArgs("Stopping the API instances", "podman", "rm", "--force", "--filter", "name=local_"+Config().AppNameShort+"_api")
 	}
 
 	// // Also, making sure Podman's internal network is removed to be able to start from fresh later on
@@ -160,8 +151,179 @@ func ensureLocalEnvReady() {
 	if IsDevAPI() {
 		// create the missing network if it doesn't exist yet
 		localEnvCtx := NewBaseContext().WithStdErrWriter(os.Stdout).WithStdOutWriter(os.Stdout).WithAllowFailure(true)
-		if !Run("Checking the 'shared-net' network existence", localEnvCtx, false, "%s", "podman network exists shared-net") {
-			Run("Creating the 'shared-net' network", localEnvCtx, false, "%s", "podman network create shared-net")
+		if !RunArgs("Checking the 'shared-net' network existence", localEnvCtx, false, "podman", "network", "exists", "shared-net") {
+			RunArgs("Creating the 'shared-net' network", localEnvCtx, false, "podman", "network", "create", "shared-net")
 		}
 	}
 }
+
+// ----------------------------------------------------------------------------
+// Hot-reloading the local API
+// ----------------------------------------------------------------------------
+
+// how long a restarted API replica should keep running before we consider it's fine, and move on to the next one
+const replicaSettleDelay = 2 * time.Second
+
+// how long a replica is given to stop by itself when restarted, before being killed
+const replicaStopTimeout = 5
+
+// the reloads requested while one is running are done as a single one, once it's over
+var (
+	devReloadMx      sync.Mutex
+	devReloading     bool // true while a reload is running
+	devReloadPending bool // true if another reload has been requested meanwhile
+)
+
+// reloads the API as a worker of the given context, unless a reload is running already - in which case another one is
+// done once it's over
+func requestDevReload(ctx CancelableContext, noServe bool) {
+	devReloadMx.Lock()
+	defer devReloadMx.Unlock()
+
+	if devReloading {
+		devReloadPending = true
+		return
+	}
+	devReloading = true
+
+	ctx.Go("reloading the API", func() {
+		for {
+			devReload(ctx, noServe)
+
+			devReloadMx.Lock()
+			if !devReloadPending || ctx.Err() != nil {
+				devReloading, devReloadPending = false, false
+				devReloadMx.Unlock()
+				return
+			}
+			devReloadPending = false
+			devReloadMx.Unlock()
+		}
+	})
+}
+
+// rebuilding the code, and if it compiles, restarting the API replicas one at a time - so that the load balancer
+// always has some of them to talk to - while the load balancer & the DB containers are left untouched
+func devReload(ctx CancelableContext, noServe bool) {
+	// not while the API is being started, nor reloaded
+	devStackMx.Lock()
+	defer devStackMx.Unlock()
+
+	// if the code does not compile, the replicas keep on running the previous binary
+	if !devBuild() {
+		Warn("The API has not been reloaded since the build failed")
+		return
+	}
+	if !IsDevAPI() || noServe {
+		return
+	}
+
+	// no replica running: they may be starting; else, the initial start may have failed, so let's start everything
+	replicas := runningReplicas()
+	if len(replicas) == 0 {
+		if devStackServing {
+			Info("No API replica running yet; the ones being started will run the new binary")
+			return
+		}
+		if hasLocalDeployment() {
+			devDown()
+			devStackServing = true
+			ctx.Go("starting the API", func() { devServe(ctx) })
+		}
+		return
+	}
+
+	// rolling the replicas, which run the binary mounted from the bin folder
+	start := time.Now()
+	for index, replica := range replicas {
+		if ctx.Err() != nil {
+			return
+		}
+		if !restartReplica(ctx, replica, fmt.Sprintf("%d/%d", index+1, len(replicas))) {
+			Error("Replica '%s' did not restart properly; the remaining ones keep running the previous binary", replica)
+			return
+		}
+	}
+
+	Info("API reloaded - %d replica(s) restarted - in %s", len(replicas), time.Since(start))
+}
+
+// returns the names of the running API replicas, without the load balancer
+func runningReplicas() (replicas []string) {
+	output := RunAndGetArgs("Listing the running API replicas", ".", false, "podman", "ps",
+		"--filter", fmt.Sprintf("name=^local_%s_api_[0-9]+$", regexp.QuoteMeta(Config().AppNameShort)), "--format", "{{.Names}}")
+
+	for line := range strings.Lines(string(output)) {
+		if replica := strings.TrimSpace(line); replica != "" {
+			replicas = append(replicas, replica)
+		}
+	}
+
+	return
+}
+
+// restarts the given replica, and returns true if it's still running after a little while
+func restartReplica(ctx CancelableContext, replica, progress string) bool {
+	// the logs of the previous run of this replica, if followed, are over
+	stopFollowingReplicaLogs(replica)
+
+	restartTime := time.Now()
+	if !QuickRunArgs(fmt.Sprintf("Restarting API replica %s", progress), "podman", "restart", "--time", fmt.Sprint(replicaStopTimeout), replica) {
+		return false
+	}
+
+	// the replica is not attached to 'podman-compose up' anymore, so let's keep on showing its logs
+	followReplicaLogs(ctx, replica, restartTime)
+
+	// giving the new binary a chance to crash at startup
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(replicaSettleDelay):
+	}
+
+	return strings.TrimSpace(string(RunAndGetArgs("Checking the restarted API replica", ".", false,
+		"podman", "inspect", "--format", "{{.State.Running}}", replica))) == "true"
+}
+
+// the contexts of the commands following the logs of the restarted replicas, by replica name
+var (
+	replicaLogFollowers   = map[string]*baseCancelableContext{}
+	replicaLogFollowersMx sync.Mutex
+)
+
+// follows the logs of the given replica since the given time, until it's restarted again, or the given context is done
+func followReplicaLogs(ctx CancelableContext, replica string, since time.Time) {
+	followerCtx, release := newBoundContext(ctx)
+	withComposeOutput(followerCtx).WithAllowFailure(true)
+
+	replicaLogFollowersMx.Lock()
+	replicaLogFollowers[replica] = followerCtx
+	replicaLogFollowersMx.Unlock()
+
+	ctx.Go("following the logs of "+replica, func() {
+		defer release()
+		RunArgs("Following the logs of "+replica, followerCtx, false,
+			"podman", "logs", "--follow", "--names", "--since", since.Format(time.RFC3339), replica)
+	})
+}
+
+// stops following the logs of the given replicas - all of them if none is given - and waits for it to be done
+func stopFollowingReplicaLogs(replicas ...string) {
+	replicaLogFollowersMx.Lock()
+	if len(replicas) == 0 {
+		replicas = slices.Collect(maps.Keys(replicaLogFollowers))
+	}
+	followers := []*baseCancelableContext{}
+	for _, replica := range replicas {
+		if follower := replicaLogFollowers[replica]; follower != nil {
+			followers = append(followers, follower)
+			delete(replicaLogFollowers, replica)
+		}
+	}
+	replicaLogFollowersMx.Unlock()
+
+	for _, follower := range followers {
+		follower.CancelAll()
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import "testing"
+
+// a config for developing an API locally, and the commands run to build & start it
+const (
+	devAPIConfig = `
+api:
+  build:
+    srcdir: api
+  localdev:
+    instances: 3
+deploying:
+  dir: deploy
+appnameshort: shop
+`
+	codegenCmd = "aldev codegen"
+	composeCmd = "podman-compose -f deploy/local/compose.yaml up --scale shop_api=3"
+)
+
+func TestDevUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		noServe  bool
+		script   func(runner *FakeRunner)
+		wantCmds []string
+	}{
+		{
+			name:     "build & serve",
+			config:   devAPIConfig,
+			wantCmds: []string{codegenCmd, composeCmd},
+		},
+		{
+			name:     "build only",
+			config:   devAPIConfig,
+			noServe:  true,
+			wantCmds: []string{codegenCmd},
+		},
+		{
+			name:     "failed build",
+			config:   devAPIConfig,
+			script:   func(runner *FakeRunner) { runner.On("aldev", "codegen").ExitCode(1) },
+			wantCmds: []string{codegenCmd},
+		},
+		{
+			name:     "no API to serve",
+			config:   "appnameshort: shop\n",
+			wantCmds: []string{codegenCmd},
+		},
+		{
+			name:     "nowhere to deploy",
+			config:   "api:\n  build:\n    srcdir: api\n",
+			wantCmds: []string{codegenCmd},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, test.config)
+			runner := useFakeRunner(t).Strict()
+			if test.script != nil {
+				test.script(runner)
+			}
+			runner.On("aldev", "codegen")
+			runner.On("podman-compose")
+
+			if err := runCatching(func() { devUp(NewBaseContext(), test.noServe) }); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertCommandLines(t, runner, test.wantCmds...)
+		})
+	}
+}
+
+func TestDevReload(t *testing.T) {
+	listReplicasCmd := joinShellWords([]string{"podman", "ps", "--filter", "name=^local_shop_api_[0-9]+$", "--format", "{{.Names}}"})
+
+	tests := []struct {
+		name     string
+		serving  bool // if the stack is being started
+		script   func(runner *FakeRunner)
+		wantCmds []string
+	}{
+		{
+			name:     "failed build",
+			script:   func(runner *FakeRunner) { runner.On("aldev", "codegen").ExitCode(1) },
+			wantCmds: []string{codegenCmd},
+		},
+		{
+			name:     "stack still starting",
+			serving:  true,
+			wantCmds: []string{codegenCmd, listReplicasCmd},
+		},
+		{
+			name: "stack not running",
+			wantCmds: []string{codegenCmd, listReplicasCmd,
+				"podman rm --force --filter name=local_shop_api_lb", "podman rm --force --filter name=local_shop_api", composeCmd},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, devAPIConfig)
+			runner := useFakeRunner(t).Strict()
+			if test.script != nil {
+				test.script(runner)
+			}
+			runner.On("aldev", "codegen")
+			runner.On("podman")
+			runner.On("podman-compose")
+			devStackServing = test.serving
+			t.Cleanup(func() { devStackServing = false })
+
+			ctx := NewBaseContext()
+			if err := runCatching(func() { devReload(ctx, false) }); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ctx.Wait() // the stack may be started in the background
+
+			assertCommandLines(t, runner, test.wantCmds...)
+		})
+	}
+}
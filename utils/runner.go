@@ -0,0 +1,103 @@
+// ----------------------------------------------------------------------------
+// The code here is about what actually runs the commands: by default, real
+// processes, but another runner - e.g. a fake one - can be plugged into the
+// contexts, to run Aldev without the real tools
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// Invocation is a command to run, as passed to a Runner
+type Invocation struct {
+	Why     string    // why the command is run
+	Command []string  // the program, then its args
+	Dir     string    // the directory to run the command from; the current one if empty
+	Env     []string  // the whole environment of the command, as KEY=VALUE; the current process' one if nil
+	Stdout  io.Writer // where the command's output goes
+	Stderr  io.Writer // where the command's errors go
+	Bound   bool      // if true, the command has to be stopped once the context given to the runner is done
+}
+
+// Runner runs commands
+type Runner interface {
+	// Run runs the given command, and returns an error if it could not start, failed or was stopped; the exit code,
+	// if any, should be available through an ExitCode() method on the error - like with *exec.ExitError - with -1 if
+	// the command has been stopped
+	Run(ctx context.Context, invocation *Invocation) error
+}
+
+// the runner given to the new contexts
+var defaultRunner Runner = &execRunner{}
+
+// SetDefaultRunner sets the runner given to the contexts created from now on - including the ones used internally
+// by QuickRun or RunAndGet; nil restores the runner of real processes
+func SetDefaultRunner(runner Runner) {
+	if runner == nil {
+		runner = &execRunner{}
+	}
+	defaultRunner = runner
+}
+
+// returns the exit code carried by the given error, if any
+func exitCodeOf(err error) (int, bool) {
+	var exitCoder interface{ ExitCode() int }
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode(), true
+	}
+
+	return 0, false
+}
+
+// ----------------------------------------------------------------------------
+// Running real processes
+// ----------------------------------------------------------------------------
+
+type execRunner struct{}
+
+func (runner *execRunner) Run(ctx context.Context, invocation *Invocation) error {
+	cmd := exec.Command(invocation.Command[0], invocation.Command[1:]...)
+	if invocation.Bound {
+		cmd = exec.CommandContext(ctx, invocation.Command[0], invocation.Command[1:]...)
+	}
+	cmd.Dir = invocation.Dir
+	cmd.Env = invocation.Env
+	cmd.Stdout = invocation.Stdout
+	cmd.Stderr = invocation.Stderr
+
+	exited := make(chan struct{})
+	defer close(exited)
+	if invocation.Bound {
+		// running the command in its own process group, so that stopping it also stops all the processes it's started;
+		// the unbound commands stay in ours, to get the terminal's signals - like a Ctrl-C - along with us
+		setProcessGroup(cmd)
+	}
+	setShellCommandLine(cmd, invocation.Command)
+	if invocation.Bound {
+		fromDirString := ""
+		if invocation.Dir != "" {
+			fromDirString = " [from " + invocation.Dir + "]"
+		}
+		cmd.Cancel = func() error {
+			Debug("Stopping%s: '%s'", fromDirString, cmd.String())
+			go func() {
+				select {
+				case <-exited:
+				case <-time.After(cmdStopGracePeriod):
+					Warn("Command [%s%s] still running %s after being asked to stop; killing it", fromDirString, cmd.String(), cmdStopGracePeriod)
+					_ = stopProcessGroup(cmd, true)
+				}
+			}()
+			return stopProcessGroup(cmd, false)
+		}
+		// not waiting forever for output pipes held by orphaned processes
+		cmd.WaitDelay = cmdStopGracePeriod + time.Second
+	}
+
+	return cmd.Run()
+}
@@ -0,0 +1,198 @@
+// ----------------------------------------------------------------------------
+// The code here is about a fake runner, to run Aldev without the real tools:
+// it records the commands it's given, and answers them with canned outputs
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// FakeRunner is a scriptable Runner: the commands it's given are recorded, and answered with the response of the
+// first rule matching them - or an empty success if none does, unless the runner is strict, e.g.:
+//
+//	runner := utils.NewFakeRunner()
+//	runner.On("git", "branch", "--show-current").Output("main\n")
+//	runner.On("git", "push").ExitCode(1).Once()
+//	utils.SetDefaultRunner(runner)
+type FakeRunner struct {
+	mx          sync.Mutex
+	rules       []*FakeResponse
+	invocations []*Invocation
+	strict      bool
+}
+
+// FakeResponse is the canned response to the commands starting with some given words
+type FakeResponse struct {
+	prefix   []string
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error
+	delay    time.Duration
+	once     bool
+	used     bool
+}
+
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// On adds a rule for the commands starting with the given words - all the commands if none is given - and returns
+// its response, to be scripted; the rules are looked at in the order they've been added
+func (runner *FakeRunner) On(commandPrefix ...string) *FakeResponse {
+	runner.mx.Lock()
+	defer runner.mx.Unlock()
+
+	response := &FakeResponse{prefix: commandPrefix}
+	runner.rules = append(runner.rules, response)
+
+	return response
+}
+
+// Strict makes the commands matched by no rule fail
+func (runner *FakeRunner) Strict() *FakeRunner {
+	runner.mx.Lock()
+	defer runner.mx.Unlock()
+
+	runner.strict = true
+	return runner
+}
+
+// Output sets what's written on the command's stdout
+func (response *FakeResponse) Output(stdout string) *FakeResponse {
+	response.stdout = stdout
+	return response
+}
+
+// Stderr sets what's written on the command's stderr
+func (response *FakeResponse) Stderr(stderr string) *FakeResponse {
+	response.stderr = stderr
+	return response
+}
+
+// ExitCode makes the command fail with the given exit code, if not 0
+func (response *FakeResponse) ExitCode(exitCode int) *FakeResponse {
+	response.exitCode = exitCode
+	return response
+}
+
+// Fail makes the command fail to start, with the given error
+func (response *FakeResponse) Fail(err error) *FakeResponse {
+	response.err = err
+	return response
+}
+
+// Delay makes the command last this long - or until it's stopped, if it's bound to its context
+func (response *FakeResponse) Delay(delay time.Duration) *FakeResponse {
+	response.delay = delay
+	return response
+}
+
+// Once makes the rule apply to the first matching command only
+func (response *FakeResponse) Once() *FakeResponse {
+	response.once = true
+	return response
+}
+
+func (runner *FakeRunner) Run(ctx context.Context, invocation *Invocation) error {
+	response, strict := runner.record(invocation)
+	if response == nil {
+		if strict {
+			return fmt.Errorf("no fake response for command: %s", joinShellWords(invocation.Command))
+		}
+		return nil
+	}
+
+	// lasting as long as required
+	if response.delay > 0 {
+		stopped := (<-chan struct{})(nil)
+		if invocation.Bound {
+			stopped = ctx.Done()
+		}
+		select {
+		case <-stopped:
+			return &FakeExitError{Code: -1}
+		case <-time.After(response.delay):
+		}
+	}
+
+	// the canned response
+	if response.err != nil {
+		return response.err
+	}
+	if response.stdout != "" && invocation.Stdout != nil {
+		_, _ = io.WriteString(invocation.Stdout, response.stdout)
+	}
+	if response.stderr != "" && invocation.Stderr != nil {
+		_, _ = io.WriteString(invocation.Stderr, response.stderr)
+	}
+	if response.exitCode != 0 {
+		return &FakeExitError{Code: response.exitCode}
+	}
+
+	return nil
+}
+
+// records the given invocation, and returns the response of the first rule matching it, if any - and whether the runner is
+// strict, to know what to do if there's none
+func (runner *FakeRunner) record(invocation *Invocation) (*FakeResponse, bool) {
+	runner.mx.Lock()
+	defer runner.mx.Unlock()
+
+	recorded := *invocation
+	recorded.Command = slices.Clone(invocation.Command)
+	runner.invocations = append(runner.invocations, &recorded)
+
+	for _, response := range runner.rules {
+		if (!response.once || !response.used) && len(invocation.Command) >= len(response.prefix) &&
+			slices.Equal(invocation.Command[:len(response.prefix)], response.prefix) {
+			response.used = true
+			return response, runner.strict
+		}
+	}
+
+	return nil, runner.strict
+}
+
+// Invocations returns the commands run so far, in order
+func (runner *FakeRunner) Invocations() []*Invocation {
+	runner.mx.Lock()
+	defer runner.mx.Unlock()
+
+	return slices.Clone(runner.invocations)
+}
+
+// CommandLines returns the commands run so far, in order, as command lines
+func (runner *FakeRunner) CommandLines() []string {
+	lines := []string{}
+	for _, invocation := range runner.Invocations() {
+		lines = append(lines, joinShellWords(invocation.Command))
+	}
+
+	return lines
+}
+
+// FakeExitError is returned by the fake runner for the commands failing with an exit code
+type FakeExitError struct {
+	Code int
+}
+
+func (err *FakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", err.Code)
+}
+
+func (err *FakeExitError) ExitCode() int {
+	return err.Code
+}
+
+// checking the runners do satisfy the interface
+var (
+	_ Runner = (*FakeRunner)(nil)
+	_ Runner = (*execRunner)(nil)
+)
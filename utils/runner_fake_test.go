@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ----------------------------------------------------------------------------
+// Test helpers
+// ----------------------------------------------------------------------------
+
+// makes the commands run with the default runner go to a new fake runner, for the duration of the test
+func useFakeRunner(t *testing.T) *FakeRunner {
+	t.Helper()
+
+	runner := NewFakeRunner()
+	SetDefaultRunner(runner)
+	t.Cleanup(func() { SetDefaultRunner(nil) })
+
+	return runner
+}
+
+// makes the test use the config read from the given YAML
+func useConfig(t *testing.T, configYAML string) *AldevConfig {
+	t.Helper()
+
+	testConfig := &AldevConfig{}
+	if errUnmarshal := yaml.Unmarshal([]byte(configYAML), testConfig); errUnmarshal != nil {
+		t.Fatalf("invalid test config: %v", errUnmarshal)
+	}
+
+	previousConfig := config
+	config = testConfig
+	t.Cleanup(func() { config = previousConfig })
+
+	return testConfig
+}
+
+// makes the test run in dry-run mode, or not
+func useDryRun(t *testing.T, isDryRun bool) {
+	t.Helper()
+
+	previousDryRun := dryRun
+	dryRun = isDryRun
+	t.Cleanup(func() { dryRun = previousDryRun })
+}
+
+// runs the given function, returning its panic as an error, like the public entry points of this package do
+func runCatching(fn func()) (err error) {
+	defer catchPanic(&err, "testing")
+	fn()
+
+	return nil
+}
+
+// checks the fake runner has been given the expected commands, in order
+func assertCommandLines(t *testing.T, runner *FakeRunner, expected ...string) {
+	t.Helper()
+
+	if actual := runner.CommandLines(); !slices.Equal(actual, expected) {
+		t.Errorf("unexpected commands:\n got: %q\nwant: %q", actual, expected)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Fake runner
+// ----------------------------------------------------------------------------
+
+func TestFakeRunner(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       func(runner *FakeRunner)
+		timeout      time.Duration
+		commands     [][]string
+		wantOutput   string
+		wantExitCode int // the exit code of the failure, if > 0, or -1 for a failure without an exit code
+		wantTimedOut bool
+	}{
+		{
+			name:     "empty success without any rule",
+			commands: [][]string{{"git", "status"}},
+		},
+		{
+			name: "output of the first matching rule",
+			script: func(runner *FakeRunner) {
+				runner.On("git", "tag").Output("v1.2.3\n")
+				runner.On("git").Output("other\n")
+			},
+			commands:   [][]string{{"git", "tag", "-l"}},
+			wantOutput: "v1.2.3\n",
+		},
+		{
+			name:       "rule for all the commands",
+			script:     func(runner *FakeRunner) { runner.On().Output("anything\n") },
+			commands:   [][]string{{"whatever", "--flag"}},
+			wantOutput: "anything\n",
+		},
+		{
+			name:         "failure with an exit code",
+			script:       func(runner *FakeRunner) { runner.On("git", "push").ExitCode(128) },
+			commands:     [][]string{{"git", "push"}},
+			wantExitCode: 128,
+		},
+		{
+			name:         "failure to start",
+			script:       func(runner *FakeRunner) { runner.On("podman").Fail(errors.New("executable file not found")) },
+			commands:     [][]string{{"podman", "ps"}},
+			wantExitCode: -1,
+		},
+		{
+			name: "rule applying once",
+			script: func(runner *FakeRunner) {
+				runner.On("git", "pull").ExitCode(1).Once()
+				runner.On("git", "pull").Output("Already up to date.\n")
+			},
+			commands:   [][]string{{"git", "pull"}, {"git", "pull"}},
+			wantOutput: "Already up to date.\n",
+		},
+		{
+			name:         "strict runner without any matching rule",
+			script:       func(runner *FakeRunner) { runner.Strict().On("git", "status").Output("") },
+			commands:     [][]string{{"git", "status"}, {"git", "stash"}},
+			wantExitCode: -1,
+		},
+		{
+			name:       "delayed command",
+			script:     func(runner *FakeRunner) { runner.On("sleep").Delay(20 * time.Millisecond).Output("awake\n") },
+			commands:   [][]string{{"sleep", "1"}},
+			wantOutput: "awake\n",
+		},
+		{
+			name:         "delayed command stopped by its timeout",
+			script:       func(runner *FakeRunner) { runner.On("sleep").Delay(time.Minute) },
+			timeout:      20 * time.Millisecond,
+			commands:     [][]string{{"sleep", "60"}},
+			wantExitCode: -1,
+			wantTimedOut: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := useFakeRunner(t)
+			if test.script != nil {
+				test.script(runner)
+			}
+
+			// running the commands in order, and keeping the outcome of the last one
+			output := new(bytes.Buffer)
+			var err error
+			expectedLines := []string{}
+			for _, command := range test.commands {
+				output.Reset()
+				ctx := NewBaseContext().WithStdOutWriter(output).WithStdErrWriter(io.Discard).WithTimeout(test.timeout)
+				err = runCatching(func() { RunArgs("testing", ctx, false, command...) })
+				expectedLines = append(expectedLines, joinShellWords(command))
+			}
+
+			assertCommandLines(t, runner, expectedLines...)
+			if output.String() != test.wantOutput {
+				t.Errorf("unexpected output: got %q, want %q", output.String(), test.wantOutput)
+			}
+
+			if test.wantExitCode == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var cmdErr *CommandError
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("expected a command error, got: %v", err)
+			}
+			if cmdErr.ExitCode != test.wantExitCode || cmdErr.TimedOut != test.wantTimedOut {
+				t.Errorf("unexpected failure: got exit code %d (timed out: %t), want %d (timed out: %t)",
+					cmdErr.ExitCode, cmdErr.TimedOut, test.wantExitCode, test.wantTimedOut)
+			}
+		})
+	}
+}
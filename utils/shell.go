@@ -5,135 +5,268 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"strings"
+	"slices"
 	"time"
+
+	core "github.com/aldesgroup/corego"
 )
 
+// Run runs the given command line - formatted with the given params - whose words are split the way a POSIX shell would
 func Run(whyRunThis string, ctx CancelableContext, logStart bool, commandAsString string, params ...any) bool {
-	// splitting the command elements as expected by the os/exec package
-	rawCommandElements := strings.Split(fmt.Sprintf(commandAsString, params...), " ")
-	command := rawCommandElements[0]
-
-	// the command args
-	commandArgs := []string{}
-	coalescedArg := ""
-	for _, rawElement := range rawCommandElements[1:] {
-		if strings.HasPrefix(rawElement, "\"") && !strings.HasSuffix(rawElement, "\"") {
-			coalescedArg = rawElement[1:]
-		} else if coalescedArg != "" {
-			coalescedArg += " " + rawElement
-			if strings.HasSuffix(rawElement, "\"") {
-				coalescedArg = coalescedArg[:len(coalescedArg)-1]
-				commandArgs = append(commandArgs, coalescedArg)
-				coalescedArg = ""
-			}
-		} else {
-			commandArgs = append(commandArgs, rawElement)
-		}
+	return RunArgs(whyRunThis, ctx, logStart, splitCommand(commandAsString, params...)...)
+}
+
+// RunArgs runs the given command, whose elements - the program, then its args - are passed as is, without any parsing
+func RunArgs(whyRunThis string, ctx CancelableContext, logStart bool, command ...string) bool {
+	core.PanicMsgIf(len(command) == 0, "Empty command for: %s", whyRunThis)
+
+	return runCmd(whyRunThis, ctx, logStart, true, command)
+}
+
+// RunShell runs the given script through the platform's shell - i.e. sh -c, or cmd /C under Windows - so that pipes,
+// redirections, env assignments, && chains or globbing can be used; the exec dir, env vars, failure & cancellation are
+// dealt with like for Run
+func RunShell(whyRunThis string, ctx CancelableContext, logStart bool, script string) bool {
+	if core.IsWindows() {
+		return RunArgs(whyRunThis, ctx, logStart, "cmd", "/C", script)
 	}
 
-	// running the command
-	return runCmd(whyRunThis, ctx, logStart, exec.CommandContext(ctx, command, commandArgs...))
+	return RunArgs(whyRunThis, ctx, logStart, "sh", "-c", script)
 }
 
 func QuickRun(whyRunThis string, commandAsString string, params ...any) bool {
+	return QuickRunArgs(whyRunThis, splitCommand(commandAsString, params...)...)
+}
+
+// QuickRunArgs is the same as QuickRun, with the command elements passed as is
+func QuickRunArgs(whyRunThis string, command ...string) bool {
 	if verbose {
-		return Run(whyRunThis, NewBaseContext().WithStdErrWriter(os.Stdout).WithStdOutWriter(os.Stdout), verbose, commandAsString, params...)
+		return RunArgs(whyRunThis, NewBaseContext().WithStdErrWriter(os.Stdout).WithStdOutWriter(os.Stdout), verbose, command...)
 	}
 
-	return Run(whyRunThis, NewBaseContext().WithStdErrWriter(io.Discard), false, commandAsString, params...)
+	return RunArgs(whyRunThis, NewBaseContext().WithStdErrWriter(io.Discard), false, command...)
 }
 
+// RunAndGet runs the given command to get its output - which is why it is run even in dry-run mode: it should not have side effects
 func RunAndGet(whyRunThis string, execDir string, logStart bool, commandAsString string, params ...any) []byte {
-	commandElements := strings.Split(fmt.Sprintf(commandAsString, params...), " ")
+	return RunAndGetArgs(whyRunThis, execDir, logStart, splitCommand(commandAsString, params...)...)
+}
+
+// RunAndGetArgs is the same as RunAndGet, with the command elements passed as is
+func RunAndGetArgs(whyRunThis string, execDir string, logStart bool, command ...string) []byte {
+	core.PanicMsgIf(len(command) == 0, "Empty command for: %s", whyRunThis)
+
 	buffer := new(bytes.Buffer)
 	ctx := NewBaseContext().WithStdOutWriter(buffer).WithExecDir(execDir).WithAllowFailure(true)
 	if !verbose {
 		ctx.WithStdErrWriter(io.Discard)
 	}
-	runCmd(whyRunThis, ctx, logStart, exec.Command(commandElements[0], commandElements[1:]...))
+	runCmd(whyRunThis, ctx, logStart, false, command)
 	return buffer.Bytes()
 }
 
-func runCmd(whyRunThis string, ctxArg CancelableContext, logStart bool, cmd *exec.Cmd) bool {
+// how long a command is given to stop by itself - after a SIGTERM - when its context is cancelled, before being killed
+const cmdStopGracePeriod = 5 * time.Second
+
+// runs the given command - with the retries & timeout set on the context, if any - and returns true if it went fine;
+// the command is bound to the context - i.e. stopped when it's cancelled - only if required
+func runCmd(whyRunThis string, ctxArg CancelableContext, logStart bool, bindToCtx bool, command []string) bool {
 	// making sure we have a non-nil context here
 	ctx := ctxArg
 	if ctx == nil {
 		ctx = NewBaseContext()
 	}
 
-	// making sure we're showing everything the command will throw
+	// the execution directory, if any
+	fromDirString := ""
+	if ctx.getExecDir() != "" {
+		fromDirString = " [from " + ctx.getExecDir() + "]"
+	}
+
+	// in dry-run mode, the commands are only printed - except the ones whose output is needed, i.e. not bound to a context
+	if dryRun && bindToCtx {
+		DryRun("Would run%s: %s", fromDirString, joinShellWords(command))
+		return true
+	}
+
+	policy := ctx.getRetryPolicy()
+	timeLimit := ctx.getTimeLimit()
+	for attempt := 1; ; attempt++ {
+		attemptString := ""
+		if policy != nil {
+			attemptString = fmt.Sprintf(" (attempt %d/%d)", attempt, policy.retries+1)
+		}
+
+		// the attempt cannot last beyond the time limit, if any
+		timeout := ctx.getTimeout()
+		if !timeLimit.IsZero() {
+			remaining := max(time.Until(timeLimit), time.Millisecond)
+			if timeout == 0 || timeout > remaining {
+				timeout = remaining
+			}
+		}
+
+		// a new command for each attempt, since a command can only be run once; with its own deadline, if any
+		attemptCtx, cancelAttempt := context.Context(ctx), context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancelAttempt = context.WithTimeout(ctx, timeout)
+		}
+		invocation := &Invocation{Why: whyRunThis, Command: command, Bound: bindToCtx || timeout > 0}
+
+		// actually running the command
+		errRun := runCmdOnce(ctx, attemptCtx, logStart, invocation, fromDirString, attemptString, core.IfThenElse(policy != nil, attempt, 0))
+		timedOut := ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded
+		cancelAttempt()
+		if errRun == nil {
+			// it went fine
+			return true
+		}
+
+		// the command has been stopped on purpose
+		exitCode, hasExitCode := exitCodeOf(errRun)
+		if !timedOut && ((logStart && hasExitCode && exitCode == -1) || ctx.Err() != nil) {
+			Info("Command canceled due to context cancellation")
+			return false
+		}
+
+		errMsg := fmt.Sprintf("Command [%s%s] failed%s: %v", fromDirString, joinShellWords(command), attemptString, errRun.Error())
+		if timedOut {
+			errMsg = fmt.Sprintf("Command [%s%s] timed out after %s%s", fromDirString, joinShellWords(command), timeout, attemptString)
+		}
+
+		// retrying, if allowed - and if there's still time for it
+		if policy.allowsRetry(attempt, errRun, timedOut) {
+			delay := policy.delayAfter(attempt)
+			if timeLimit.IsZero() || time.Until(timeLimit) > delay {
+				Warn("%s; retrying in %s", errMsg, delay)
+				select {
+				case <-ctx.Done():
+					Info("Command canceled due to context cancellation")
+					return false
+				case <-time.After(delay):
+					continue
+				}
+			}
+			errMsg += "; no time left to retry"
+		}
+
+		// let's re-run to have more info, if not printed on stderr at first
+		if ctx.isReRun() {
+			Error("%s", errMsg)
+			RunArgs("Re-running the command to get the error logs",
+				NewBaseContext().WithStdErrWriter(os.Stderr).WithExecDir(ctx.getExecDir()), true, command...)
+		} else {
+			if !ctx.isAllowingFailure() {
+				cmdErr := &CommandError{Why: whyRunThis, Command: command, Dir: ctx.getExecDir(), ExitCode: -1, TimedOut: timedOut, Err: errRun, msg: errMsg}
+				if hasExitCode {
+					cmdErr.ExitCode = exitCode
+				}
+				panic(cmdErr)
+			} else {
+				Error("%s", errMsg)
+			}
+		}
+
+		return false
+	}
+}
+
+// runs the given command once - with the context's runner, the given context being the one the command is bound to,
+// if it is - and returns its error if it failed
+func runCmdOnce(ctx CancelableContext, runCtx context.Context, logStart bool, invocation *Invocation, fromDirString, attemptString string, attempt int) error {
+	// making sure we're showing everything the command will throw - with its own buffer for its incomplete lines
 	if ctx.getStdOutWriter() != nil {
-		cmd.Stdout = ctx.getStdOutWriter()
+		invocation.Stdout = commandOutput(ctx.getStdOutWriter())
 	} else {
-		cmd.Stdout = os.Stdout
+		invocation.Stdout = os.Stdout
 	}
 
 	if ctx.getStdErrWriter() != nil {
-		cmd.Stderr = ctx.getStdErrWriter()
+		invocation.Stderr = commandOutput(ctx.getStdErrWriter())
 	} else {
-		cmd.Stderr = os.Stderr
+		invocation.Stderr = os.Stderr
 	}
 
 	// changing the execution directory if needed
-	fromDirString := ""
-	if ctx.getExecDir() != "" {
-		cmd.Dir = ctx.getExecDir()
-		fromDirString = " [from " + cmd.Dir + "]"
-	}
+	invocation.Dir = ctx.getExecDir()
 
-	// passing the env vars, if any
-	for _, envVar := range ctx.getEnvVars() {
-		cmd.Env = append(os.Environ(), envVar)
-	}
+	// passing the env vars, if any - a nil env meaning the current process' one
+	invocation.Env = ctx.getEnvVars()
 
 	// bit of logging
+	commandString := joinShellWords(invocation.Command)
 	if logStart {
 		// but only in verbose mode
 		if verbose {
-			StepWithPreamble(whyRunThis, "--- [SH.RUN]> Starting%s: '%s'", fromDirString, cmd.String())
+			StepWithPreamble(invocation.Why, "--- [SH.RUN]> Starting%s: '%s'%s", fromDirString, commandString, attemptString)
 		}
 	}
 
+	// keeping track of the running commands, so that the context cancellation can wait for them to exit
+	defer ctx.AddWorker("command [" + commandString + "]")()
+
+	// keeping the end of what's written on stderr, for the journal
+	stderrTail := &tailWriter{}
+	stderr := invocation.Stderr
+	invocation.Stderr = io.MultiWriter(stderr, stderrTail)
+
 	start := time.Now()
 
-	// actually running the command
-	if errRun := cmd.Run(); errRun != nil {
-		exitErr, ok := errRun.(*exec.ExitError)
-		if logStart && ok && exitErr.ExitCode() == -1 {
-			Info("Command canceled due to context cancellation")
-		} else {
-			errMsg := fmt.Sprintf("Command [%s%s] failed: %v", fromDirString, cmd.String(), errRun.Error())
-			// let's re-run to have more info, if not printed on stderr at first
-			if ctx.isReRun() {
-				Error("%s", errMsg)
-				Run("Re-running the command to get the error logs",
-					NewBaseContext().WithStdErrWriter(os.Stderr).WithExecDir(ctx.getExecDir()), true, "%s", cmd.String())
-			} else {
-				if !ctx.isAllowingFailure() {
-					panic(errMsg)
-				} else {
-					Error("%s", errMsg)
-				}
-			}
-		}
+	// writing the command's last incomplete lines, if its output is buffered
+	defer flushOutput(invocation.Stdout)
+	defer flushOutput(stderr)
 
-		return false
+	// actually running the command, and keeping track of it
+	errRun := ctx.getRunner().Run(runCtx, invocation)
+	recordInJournal(ctx, invocation, attempt, start, errRun, stderrTail)
+	if errRun != nil {
+		return errRun
 	}
 
 	// bit of logging, only in verbose mode
 	if verbose {
 		if logStart {
-			Step("--- [SH.RUN]> Finished%s: '%s' in %s", fromDirString, cmd.String(), time.Since(start))
+			Step("--- [SH.RUN]> Finished%s: '%s' in %s%s", fromDirString, commandString, time.Since(start), attemptString)
 		} else {
-			StepWithPreamble(whyRunThis, "--- [SH.RUN]> Done%s: '%s' in %s", fromDirString, cmd.String(), time.Since(start))
+			StepWithPreamble(invocation.Why, "--- [SH.RUN]> Done%s: '%s' in %s%s", fromDirString, commandString, time.Since(start), attemptString)
 		}
 	}
 
-	// it went fine
-	return true
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Retries
+// ----------------------------------------------------------------------------
+
+// the default delay before retrying a failed command
+const defaultRetryDelay = time.Second
+
+// how a failed command should be retried
+type retryPolicy struct {
+	retries     int           // how many times the command can be retried
+	delay       time.Duration // the delay before the 1st retry, doubled for each new retry
+	onExitCodes []int         // the exit codes leading to a retry; any failure if empty
+}
+
+// returns true if the command can be retried after the given failed attempt
+func (policy *retryPolicy) allowsRetry(attempt int, errRun error, timedOut bool) bool {
+	if policy == nil || attempt > policy.retries {
+		return false
+	}
+	if len(policy.onExitCodes) == 0 || timedOut {
+		return true
+	}
+	exitCode, hasExitCode := exitCodeOf(errRun)
+
+	return hasExitCode && slices.Contains(policy.onExitCodes, exitCode)
+}
+
+// returns the delay to wait after the given failed attempt
+func (policy *retryPolicy) delayAfter(attempt int) time.Duration {
+	return policy.delay << (attempt - 1)
 }
@@ -0,0 +1,35 @@
+//go:build !windows
+
+// ----------------------------------------------------------------------------
+// The code here is about stopping the commands run by Aldev, along with all
+// the processes they've started, on Unix-like systems
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// makes the command run in its own process group, so that all its children can be signaled at once
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// the shell's arguments are passed as regular ones on Unix-like systems
+func setShellCommandLine(_ *exec.Cmd, _ []string) {}
+
+// asks the command's whole process group to stop - with SIGTERM - or kills it - with SIGKILL
+func stopProcessGroup(cmd *exec.Cmd, kill bool) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	signal := syscall.SIGTERM
+	if kill {
+		signal = syscall.SIGKILL
+	}
+
+	// a negative PID means the whole process group
+	return syscall.Kill(-cmd.Process.Pid, signal)
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+// ----------------------------------------------------------------------------
+// The code here is about stopping the commands run by Aldev, along with all
+// the processes they've started, on Windows
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// makes the command run in its own process group, so that its children can be stopped with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// cmd.exe does not parse its command line like the other programs do: the script given to 'cmd /C' is passed as is,
+// rather than quoted like a regular argument
+func setShellCommandLine(cmd *exec.Cmd, command []string) {
+	if len(command) == 3 && command[0] == "cmd" && command[1] == "/C" {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.CmdLine = `cmd /S /C "` + command[2] + `"`
+	}
+}
+
+// asks the command's whole process group to stop - with a CTRL_BREAK, since the console programs have no window to
+// receive a WM_CLOSE - or kills its whole process tree; it's killed right away if it cannot be asked to stop
+func stopProcessGroup(cmd *exec.Cmd, kill bool) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	// the group created with CREATE_NEW_PROCESS_GROUP has the ID of the command's process
+	if !kill {
+		errBreak := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(cmd.Process.Pid))
+		if errBreak == nil {
+			return nil
+		}
+		Debug("Could not send a CTRL_BREAK to the process group %d (%v); killing it", cmd.Process.Pid, errBreak)
+	}
+
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
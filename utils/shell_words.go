@@ -0,0 +1,126 @@
+// ----------------------------------------------------------------------------
+// The code here is about splitting command lines into words, the way a POSIX
+// shell does it, to pass them to the os/exec package
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	core "github.com/aldesgroup/corego"
+)
+
+// splits a command line into words, the way a POSIX shell would - but without any expansion, globbing or redirection:
+// - the words are separated by blanks, i.e. spaces, tabs or new lines, whatever their number
+// - single quotes keep everything they enclose literally
+// - double quotes keep everything they enclose literally, except for the backslash-escaped \ " $ ` characters
+// - outside of quotes, a backslash keeps the next character literally, and a backslash-newline is a line continuation
+// - quotes can be used to pass empty words, e.g. git commit -m ""
+func splitShellWords(line string) ([]string, error) {
+	words := []string{}
+	current := &strings.Builder{}
+	inWord := false // needed to tell an empty word - e.g. "" - from no word at all
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		switch char := runes[i]; char {
+		case ' ', '\t', '\n', '\r':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+
+		case '\\':
+			inWord = true
+			if i+1 < len(runes) {
+				i++
+				if runes[i] != '\n' { // line continuation
+					current.WriteRune(runes[i])
+				}
+			} else {
+				current.WriteRune(char) // a trailing backslash is kept as is
+			}
+
+		case '\'':
+			inWord = true
+			end := indexRune(runes, '\'', i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in: %s", line)
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+
+		case '"':
+			inWord = true
+			closed := false
+			for i++; i < len(runes) && !closed; i++ {
+				switch {
+				case runes[i] == '"':
+					closed = true
+					i-- // the outer loop moves forward
+				case runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`\n", runes[i+1]):
+					i++
+					if runes[i] != '\n' {
+						current.WriteRune(runes[i])
+					}
+				default:
+					current.WriteRune(runes[i])
+				}
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote in: %s", line)
+			}
+
+		default:
+			inWord = true
+			current.WriteRune(char)
+		}
+	}
+
+	if inWord {
+		words = append(words, current.String())
+	}
+
+	return words, nil
+}
+
+// returns the index of the first given rune, starting from the given index, or -1
+func indexRune(runes []rune, searched rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == searched {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// formats the given command line, and splits it into words, failing if the command is malformed or empty
+func splitCommand(commandAsString string, params ...any) []string {
+	return splitCommandLine(fmt.Sprintf(commandAsString, params...))
+}
+
+// splits the given command line - used as is - into words, failing if the command is malformed or empty
+func splitCommandLine(commandLine string) []string {
+	words, errSplit := splitShellWords(commandLine)
+	core.PanicMsgIfErr(errSplit, "Invalid command")
+	core.PanicMsgIf(len(words) == 0, "Empty command: '%s'", commandLine)
+
+	return words
+}
+
+// joins the given words into a command line, quoting them when needed, so that splitShellWords gives them back
+func joinShellWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if word != "" && !strings.ContainsAny(word, " \t\n\r'\"\\$`|&;<>()*?[]#~{}") {
+			quoted[i] = word
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -4,6 +4,7 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"text/template"
@@ -23,13 +24,18 @@ func EnsureFileFromTemplateAndContext(context any, filepath, tpl string, params
 	tmpl, errTpl := template.New(filepath).Parse(content)
 	core.PanicIfErr(errTpl)
 
-	// Create a new file to write the result
-	outputFile, errCreate := os.Create(filepath)
-	core.PanicIfErr(errCreate)
-	defer outputFile.Close()
-
 	// Execute the template with the data
-	core.PanicIfErr(tmpl.Execute(outputFile, context))
+	buffer := new(bytes.Buffer)
+	core.PanicIfErr(tmpl.Execute(buffer, context))
+
+	// in dry-run mode, only showing what would change
+	if dryRun {
+		planFileWrite(filepath, buffer.Bytes())
+		return
+	}
+
+	// writing the result
+	core.PanicIfErr(os.WriteFile(filepath, buffer.Bytes(), 0o666))
 }
 
 func EnsureFileFromTemplate(filepath, tpl string, params ...any) {
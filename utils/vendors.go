@@ -5,11 +5,10 @@
 package utils
 
 import (
+	"fmt"
 	"io"
-	"os"
 	"path"
 	"strings"
-	"sync"
 	"time"
 
 	core "github.com/aldesgroup/corego"
@@ -19,32 +18,38 @@ const (
 	versionFILENAME = "version.json"
 )
 
-// Fetching the required vendored libraries
-func fetchVendoredLibraries(ctx CancelableContext) {
-	// making sure we recover any big crashing error
-	defer Recover(ctx, "fetching / refreshing the vendors")
+// the git commands going over the network can hang, or fail for a transient reason
+const (
+	vendorGitTIMEOUT    = 2 * time.Minute
+	vendorGitRETRIES    = 2
+	vendorGitRETRYDELAY = 2 * time.Second
+)
 
+func withVendorNetworkPolicy(ctx CancelableContext) CancelableContext {
+	return ctx.WithTimeout(vendorGitTIMEOUT).WithRetries(vendorGitRETRIES, vendorGitRETRYDELAY)
+}
+
+// Fetching the required vendored libraries
+func fetchVendoredLibraries(ctx CancelableContext) error {
 	// LFG
 	start := time.Now()
 
 	// fetching / refreshing all the vendors in parallel
-	wg := new(sync.WaitGroup)
+	group := new(errorGroup)
 	for _, vendor := range Config().Vendors {
-		wg.Add(1)
-		go func(v *VendorConfig) {
-			defer wg.Done()
-			fetchVendor(ctx, v)
-		}(vendor)
+		group.Go(func() error { fetchVendor(ctx, vendor); return nil }, "fetching / refreshing vendor '%s'", vendor.Repo)
+	}
+	if errFetch := group.Wait(); errFetch != nil {
+		return errFetch
 	}
-	wg.Wait()
 
 	// we're done
 	Info("Done fetching / refreshing the vendors in %s", time.Since(start))
+
+	return nil
 }
 
 func fetchVendor(ctx CancelableContext, vendor *VendorConfig) {
-	// making sure we recover any big crashing error
-	defer Recover(ctx, "fetching / refreshing vendor '%s'", vendor)
 
 	// getting the repo name
 	repoName := path.Base(vendor.Repo)
@@ -55,42 +60,49 @@ func fetchVendor(ctx CancelableContext, vendor *VendorConfig) {
 
 	// if it exists, git pulling within it
 	if repoExistsInCache {
-		Run("Ensuring the main branch in the '"+repoName+"' repo",
+		RunArgs("Ensuring the main branch in the '"+repoName+"' repo",
 			NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithStdOutWriter(io.Discard),
 			false,
-			"git checkout main")
+			"git", "checkout", "main")
 
-		Run("refreshing the cached '"+repoName+"' repo",
-			NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithStdOutWriter(io.Discard),
+		RunArgs("refreshing the cached '"+repoName+"' repo",
+			withVendorNetworkPolicy(NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithStdOutWriter(io.Discard)),
 			false,
-			"git pull")
+			"git", "pull")
 
 	} else { // if not, git clone it into temp folder
+		// in dry-run mode, there won't be any clone to inspect, so we cannot tell more about what would be done
+		if IsDryRun() {
+			DryRun("Would clone the '%s' repo into '%s', then install its '%s' version into '%s'",
+				vendor.Repo, repoCachePath, vendor.Version, path.Join(vendor.To, repoName))
+			return
+		}
+
 		firstSlashIndex := strings.Index(vendor.Repo, "/")
-		Run("git-cloning / caching the '"+repoName+"' repo",
-			NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(GetCacheDir()),
+		RunArgs("git-cloning / caching the '"+repoName+"' repo",
+			withVendorNetworkPolicy(NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(GetCacheDir()).WithOutputPrefix(repoName)),
 			false,
-			"git clone git@%s:%s.git", vendor.Repo[:firstSlashIndex], vendor.Repo[firstSlashIndex+1:]) // TODO handle https for public repos
+			"git", "clone", fmt.Sprintf("git@%s:%s.git", vendor.Repo[:firstSlashIndex], vendor.Repo[firstSlashIndex+1:])) // TODO handle https for public repos
 	}
 
 	// switch branch, if one if specified
 	if vendor.Branch != "" {
-		Run("checking out the '"+vendor.Branch+"' branch",
-			NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath),
+		RunArgs("checking out the '"+vendor.Branch+"' branch",
+			NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithOutputPrefix(repoName),
 			false,
-			"git checkout %s", vendor.Branch)
-		Run("refreshing the branch's code",
-			NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithStdOutWriter(io.Discard),
+			"git", "checkout", vendor.Branch)
+		RunArgs("refreshing the branch's code",
+			withVendorNetworkPolicy(NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithStdOutWriter(io.Discard)),
 			false,
-			"git pull")
+			"git", "pull")
 	}
 
 	// get the latest version
-	allVersions := strings.Split(string(RunAndGet("Getting the latest version", repoCachePath, false, "git tag -l --sort -version:refname")), "\n")
+	allVersions := strings.Split(string(RunAndGetArgs("Getting the latest version", repoCachePath, false, "git", "tag", "-l", "--sort", "-version:refname")), "\n")
 	latestVersion := &versionObject{Value: allVersions[0], Commit: lastCommit(repoCachePath, core.IfThenElse(vendor.Branch != "", vendor.Branch, "main"))}
 
 	// the target directory
-	vendorDir := core.EnsureDir(vendor.To, repoName)
+	vendorDir := EnsureDir(vendor.To, repoName)
 
 	// checking the current version
 	versionFileName := path.Join(vendorDir, versionFILENAME)
@@ -119,10 +131,10 @@ func fetchVendor(ctx CancelableContext, vendor *VendorConfig) {
 			}
 
 			// checking out the required version
-			Run("checking out the right '"+repoName+"' version",
-				NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath),
+			RunArgs("checking out the right '"+repoName+"' version",
+				NewBaseContext().WithStdErrWriter(io.Discard).WithExecDir(repoCachePath).WithOutputPrefix(repoName),
 				false,
-				"git checkout %s", vendor.Version)
+				"git", "checkout", vendor.Version)
 
 			// setting up the next version
 			nextVersion = &versionObject{Value: vendor.Version, Commit: lastCommit(repoCachePath, vendor.Version)}
@@ -132,15 +144,16 @@ func fetchVendor(ctx CancelableContext, vendor *VendorConfig) {
 	if nextVersion != nil {
 		// removing the previous vendor version first
 		Debug("Cleaning '%s' first, if needed", vendor.To)
-		core.PanicIfErr(os.RemoveAll(path.Join(vendor.To, repoName)))
+		core.PanicIfErr(RemoveAll(path.Join(vendor.To, repoName)))
 
 		// copying the new vendor code + version file
-		QuickRun("Copying this repo into project: "+repoName, "%s %s/%s/. %s", core.CopyCmd(), repoCachePath, vendor.From, vendorDir)
-		core.WriteJsonObjToFile(versionFileName, nextVersion)
+		QuickRunArgs("Copying this repo into project: "+repoName,
+			append(strings.Fields(core.CopyCmd()), fmt.Sprintf("%s/%s/.", repoCachePath, vendor.From), vendorDir)...)
+		WriteJSONObjToFile(versionFileName, nextVersion)
 
 		// removing the vendor's vendors, if any
 		Debug("Removing project %s's vendor folder, if any", repoName)
-		core.PanicIfErr(os.RemoveAll(path.Join(vendorDir, "vendor")))
+		core.PanicIfErr(RemoveAll(path.Join(vendorDir, "vendor")))
 
 		// bit of logging
 		if currentVersion != nil {
@@ -156,6 +169,5 @@ func fetchVendor(ctx CancelableContext, vendor *VendorConfig) {
 
 // get the latest commit
 func lastCommit(repoPath string, branchOrTag string) string {
-	commitBytes := RunAndGet("Getting the latest commit", repoPath, false, "%s", "git rev-parse "+branchOrTag)
-	return string(commitBytes[:len(commitBytes)-1])
+	return strings.TrimSpace(string(RunAndGetArgs("Getting the latest commit", repoPath, false, "git", "rev-parse", branchOrTag)))
 }
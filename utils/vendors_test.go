@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	core "github.com/aldesgroup/corego"
+)
+
+func TestFetchVendor(t *testing.T) {
+	// the commands run to find the versions available, in order
+	const (
+		tagsCmd       = "git tag -l --sort -version:refname"
+		lastCommitCmd = "git rev-parse main"
+	)
+
+	tests := []struct {
+		name       string
+		vendor     VendorConfig
+		cached     bool // if the vendor's repo has already been cloned
+		dryRun     bool
+		script     func(runner *FakeRunner)
+		wantCmds   []string
+		wantCopy   bool // if the repo should be copied into the project, after the commands above
+		wantErrMsg string
+	}{
+		{
+			name:     "cached repo, latest version",
+			vendor:   VendorConfig{Repo: "github.com/acme/mylib", Version: "latest"},
+			cached:   true,
+			wantCmds: []string{"git checkout main", "git pull", tagsCmd, lastCommitCmd},
+			wantCopy: true,
+		},
+		{
+			name:     "cached repo, pinned version",
+			vendor:   VendorConfig{Repo: "github.com/acme/mylib", Version: "v1.1.0"},
+			cached:   true,
+			wantCmds: []string{"git checkout main", "git pull", tagsCmd, lastCommitCmd, "git checkout v1.1.0", "git rev-parse v1.1.0"},
+			wantCopy: true,
+		},
+		{
+			name:     "cached repo, on a branch",
+			vendor:   VendorConfig{Repo: "github.com/acme/mylib", Branch: "next", Version: "latest"},
+			cached:   true,
+			wantCmds: []string{"git checkout main", "git pull", "git checkout next", "git pull", tagsCmd, "git rev-parse next"},
+			wantCopy: true,
+		},
+		{
+			name:       "cached repo, unknown version",
+			vendor:     VendorConfig{Repo: "github.com/acme/mylib", Version: "v9.9.9"},
+			cached:     true,
+			wantCmds:   []string{"git checkout main", "git pull", tagsCmd, lastCommitCmd},
+			wantErrMsg: "Required version 'v9.9.9' does not exist in project 'mylib'",
+		},
+		{
+			name:       "cached repo, failed checkout",
+			vendor:     VendorConfig{Repo: "github.com/acme/mylib", Version: "latest"},
+			cached:     true,
+			script:     func(runner *FakeRunner) { runner.On("git", "checkout").ExitCode(1) },
+			wantCmds:   []string{"git checkout main"},
+			wantErrMsg: "git checkout main] failed",
+		},
+		{
+			name:     "cached repo, in dry-run mode",
+			vendor:   VendorConfig{Repo: "github.com/acme/mylib", Version: "latest"},
+			cached:   true,
+			dryRun:   true,
+			wantCmds: []string{tagsCmd, lastCommitCmd},
+		},
+		{
+			name:     "uncached repo",
+			vendor:   VendorConfig{Repo: "github.com/acme/mylib", Version: "latest"},
+			wantCmds: []string{"git clone git@github.com:acme/mylib.git", tagsCmd, lastCommitCmd},
+			wantCopy: true,
+		},
+		{
+			name:     "uncached repo, in dry-run mode",
+			vendor:   VendorConfig{Repo: "github.com/acme/mylib", Version: "latest"},
+			dryRun:   true,
+			wantCmds: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the cache & the project directories
+			previousCacheDir := GetCacheDir()
+			SetCacheDir(t.TempDir())
+			t.Cleanup(func() { SetCacheDir(previousCacheDir) })
+			repoCachePath := path.Join(GetCacheDir(), "mylib")
+			if test.cached {
+				if errMkdir := os.MkdirAll(repoCachePath, 0o755); errMkdir != nil {
+					t.Fatal(errMkdir)
+				}
+			}
+			vendor := test.vendor
+			vendor.To = path.Join(t.TempDir(), "vendor")
+			useDryRun(t, test.dryRun)
+
+			// the repo's versions
+			runner := useFakeRunner(t)
+			if test.script != nil {
+				test.script(runner)
+			}
+			runner.On("git", "tag").Output("v1.2.0\nv1.1.0\nv1.0.0\n")
+			runner.On("git", "rev-parse").Output("0123456789abcdef\n")
+
+			err := runCatching(func() { fetchVendor(NewBaseContext(), &vendor) })
+
+			wantCmds := test.wantCmds
+			if test.wantCopy {
+				wantCmds = append(wantCmds, joinShellWords(append(strings.Fields(core.CopyCmd()),
+					fmt.Sprintf("%s/%s/.", repoCachePath, vendor.From), EnsureDir(vendor.To, "mylib"))))
+			}
+			assertCommandLines(t, runner, wantCmds...)
+
+			switch {
+			case test.wantErrMsg == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.wantErrMsg != "" && (err == nil || !strings.Contains(err.Error(), test.wantErrMsg)):
+				t.Errorf("unexpected error: got %v, want it to contain %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
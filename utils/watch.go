@@ -1,10 +1,440 @@
+// ----------------------------------------------------------------------------
+// The code here is about watching files & folders for changes: the folders
+// are watched recursively - the new subfolders included - and the bursts of
+// changes are coalesced into a single batch, once things have settled down
+// ----------------------------------------------------------------------------
 package utils
 
 import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
 	core "github.com/aldesgroup/corego"
 	"github.com/fsnotify/fsnotify"
 )
 
+// by default, how long there should be no change before the batch of changes is handed over
+const defaultWatchQuietPeriod = 300 * time.Millisecond
+
+// WatchOp tells how a watched path has changed
+type WatchOp uint8
+
+const (
+	WatchCreate WatchOp = 1 << iota
+	WatchWrite
+	WatchRemove
+	WatchRename
+)
+
+func (op WatchOp) String() string {
+	names := []string{}
+	for _, known := range []struct {
+		op   WatchOp
+		name string
+	}{{WatchCreate, "CREATE"}, {WatchWrite, "WRITE"}, {WatchRemove, "REMOVE"}, {WatchRename, "RENAME"}} {
+		if op&known.op != 0 {
+			names = append(names, known.name)
+		}
+	}
+
+	return strings.Join(names, "|")
+}
+
+// WatchEvent is a change on a watched path; the ops of all the changes on the same path within a batch are combined
+type WatchEvent struct {
+	Path string
+	Op   WatchOp
+}
+
+func (event *WatchEvent) String() string {
+	return fmt.Sprintf("%s %s", event.Op, event.Path)
+}
+
+// DescribeWatchEvents returns a short description of the given batch of changes, for logging
+func DescribeWatchEvents(events []*WatchEvent) string {
+	const maxDescribed = 5
+	descriptions := []string{}
+	for _, event := range events[:min(len(events), maxDescribed)] {
+		descriptions = append(descriptions, event.String())
+	}
+	if len(events) > maxDescribed {
+		descriptions = append(descriptions, fmt.Sprintf("and %d more", len(events)-maxDescribed))
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// ----------------------------------------------------------------------------
+// Watcher
+// ----------------------------------------------------------------------------
+
+// Watcher watches files, and folders recursively, and hands over the changes by batches
+type Watcher struct {
+	name        string                     // what's being watched, for the logs
+	onChanges   func(events []*WatchEvent) // called with each batch of changes
+	files       map[string]bool            // the files watched on their own
+	roots       []string                   // the folders watched recursively
+	quietPeriod time.Duration              // how long there should be no change before a batch is handed over
+	skipDir     func(dirPath string) bool  // the folders not to watch, with their subfolders
+	keep        func(event *WatchEvent) bool
+	refreshOn   func(event *WatchEvent) bool // tells which changes may change the skipped folders
+	fsWatcher   *fsnotify.Watcher            // nil when polling for the changes
+	treeDirs    map[string]bool              // the folders currently watched as part of the trees
+	snapshot    map[string]*watchedFileState // the state of the watched files at the last poll, when polling
+	newFiles    []string                     // the files to watch on their own from now on, not taken into account yet
+	newFilesMx  sync.Mutex                   // guarding newFiles, which can be added to from any goroutine
+	newFilesIn  chan struct{}                // tells the watching loop there are new files to watch
+	done        chan struct{}                // closed once the watching is over
+}
+
+// NewWatcher returns a watcher - to be started - of the given files & folders, the latter being watched recursively;
+// the given function is called with each batch of changes
+func NewWatcher(name string, onChanges func(events []*WatchEvent), paths ...string) *Watcher {
+	watcher := &Watcher{name: name, onChanges: onChanges, files: map[string]bool{}, quietPeriod: defaultWatchQuietPeriod,
+		newFilesIn: make(chan struct{}, 1), done: make(chan struct{})}
+	for _, watchedPath := range paths {
+		watchedPath = filepath.Clean(watchedPath)
+		if core.DirExists(watchedPath) {
+			watcher.roots = append(watcher.roots, watchedPath)
+		} else {
+			watcher.files[watchedPath] = true
+		}
+	}
+
+	return watcher
+}
+
+// sets how long there should be no change before a batch of changes is handed over
+func (watcher *Watcher) WithQuietPeriod(quietPeriod time.Duration) *Watcher {
+	watcher.quietPeriod = quietPeriod
+	return watcher
+}
+
+// sets which folders should not be watched, nor their subfolders
+func (watcher *Watcher) WithSkippedDirs(skipDir func(dirPath string) bool) *Watcher {
+	watcher.skipDir = skipDir
+	return watcher
+}
+
+// sets which changes should be handed over
+func (watcher *Watcher) WithEventFilter(keep func(event *WatchEvent) bool) *Watcher {
+	watcher.keep = keep
+	return watcher
+}
+
+// sets which changes may change the skipped folders - e.g. the ones on a .gitignore file - so that the watched
+// folders are refreshed after them
+func (watcher *Watcher) WithTreeRefresh(refreshOn func(event *WatchEvent) bool) *Watcher {
+	watcher.refreshOn = refreshOn
+	return watcher
+}
+
+// Start starts watching, as a worker of the given context, until it's done; the returned channel is closed once
+// the watching is over. The changes are polled for, rather than notified by the system, if required, or if they
+// could not be notified - e.g. on a network filesystem, or a folder mounted into a VM or a container
+func (watcher *Watcher) Start(ctx CancelableContext) <-chan struct{} {
+	if reason := watcher.pollingReason(); reason != "" {
+		Info("Polling for the changes every %s while %s, since %s", watchPollInterval, watcher.name, reason)
+		watcher.snapshot = watcher.scan()
+	} else {
+		Debug("%s: %d folders watched", watcher.name, len(watcher.treeDirs))
+	}
+
+	done := watcher.done
+	ctx.Go(watcher.name, func() {
+		defer close(done)
+		if watcher.fsWatcher != nil {
+			defer func() { _ = watcher.fsWatcher.Close() }()
+		}
+
+		watcher.loop(ctx)
+	})
+
+	return done
+}
+
+// returns why the changes have to be polled for, if they do - else, the changes are notified by the system from now on
+func (watcher *Watcher) pollingReason() string {
+	if watchPolling {
+		return "it's been required"
+	}
+
+	// the changes made on some filesystems are not notified
+	for _, dir := range append(core.GetSortedKeys(watcher.fileDirs()), watcher.roots...) {
+		if filesystem := unnotifiedFilesystem(dir); filesystem != "" {
+			return fmt.Sprintf("'%s' is on a '%s' filesystem", dir, filesystem)
+		}
+	}
+
+	// the system may not be able to notify us
+	fsWatcher, errNew := fsnotify.NewWatcher()
+	if errNew != nil {
+		return fmt.Sprintf("the changes cannot be notified (%v)", errNew)
+	}
+	watcher.fsWatcher = fsWatcher
+	watcher.treeDirs = map[string]bool{}
+
+	// the files are watched through their folder, since many editors replace the files they save
+	for _, dir := range core.GetSortedKeys(watcher.fileDirs()) {
+		Debug("Watching path: %s", dir)
+		if errAdd := fsWatcher.Add(dir); errAdd != nil {
+			return watcher.notNotified(dir, errAdd)
+		}
+	}
+	for _, root := range watcher.roots {
+		if errAdd := watcher.addTree(root); errAdd != nil {
+			return watcher.notNotified(root, errAdd)
+		}
+	}
+
+	return ""
+}
+
+// gives up on the system notifications
+func (watcher *Watcher) notNotified(watchedPath string, errAdd error) string {
+	_ = watcher.fsWatcher.Close()
+	watcher.fsWatcher = nil
+
+	return fmt.Sprintf("the changes in '%s' cannot be notified (%v)", watchedPath, errAdd)
+}
+
+func (watcher *Watcher) loop(ctx CancelableContext) {
+	// the changes since the last batch
+	pending := map[string]WatchOp{}
+	quietTimer := time.NewTimer(0)
+	<-quietTimer.C
+	defer quietTimer.Stop()
+	addPending := func(event *WatchEvent) {
+		if event != nil {
+			pending[event.Path] |= event.Op
+			quietTimer.Reset(watcher.quietPeriod)
+		}
+	}
+
+	// the changes come either from the system's notifications, or from polling
+	var fsEvents <-chan fsnotify.Event
+	var fsErrors <-chan error
+	var pollTicks <-chan time.Time
+	if watcher.fsWatcher != nil {
+		fsEvents, fsErrors = watcher.fsWatcher.Events, watcher.fsWatcher.Errors
+	} else {
+		pollTicker := time.NewTicker(watchPollInterval)
+		defer pollTicker.Stop()
+		pollTicks = pollTicker.C
+	}
+
+	for {
+		select {
+		case fsEvent, ok := <-fsEvents:
+			if !ok {
+				return
+			}
+			addPending(watcher.handle(fsEvent))
+
+		case errWatch, ok := <-fsErrors:
+			if !ok {
+				return
+			}
+			Error("Error while %s: %v", watcher.name, errWatch)
+
+		case <-watcher.newFilesIn:
+			watcher.addFiles(watcher.takeNewFiles())
+
+		case <-pollTicks:
+			current := watcher.scan()
+			for _, event := range diffSnapshots(watcher.snapshot, current) {
+				addPending(watcher.filtered(event))
+			}
+			watcher.snapshot = current
+
+		case <-quietTimer.C:
+			events := []*WatchEvent{}
+			for _, changedPath := range core.GetSortedKeys(pending) {
+				events = append(events, &WatchEvent{Path: changedPath, Op: pending[changedPath]})
+			}
+			pending = map[string]WatchOp{}
+			watcher.onChanges(events)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deals with the given fsnotify event, and returns the corresponding change, if it should be handed over
+func (watcher *Watcher) handle(fsEvent fsnotify.Event) *WatchEvent {
+	event := &WatchEvent{Path: filepath.Clean(fsEvent.Name), Op: toWatchOp(fsEvent.Op)}
+	if event.Op == 0 { // e.g. a mere chmod
+		return nil
+	}
+
+	// a file watched on its own
+	if watcher.files[event.Path] {
+		return watcher.filtered(event)
+	}
+
+	// else, this should be within a tree
+	if !watcher.treeDirs[filepath.Dir(event.Path)] || watcher.isSkipped(event.Path) {
+		return nil
+	}
+
+	// keeping the watched folders up to date
+	if event.Op&WatchCreate != 0 && core.DirExists(event.Path) {
+		if errAdd := watcher.addTree(event.Path); errAdd != nil {
+			Warn("Could not watch '%s': %v", event.Path, errAdd)
+		}
+	}
+	if event.Op&(WatchRemove|WatchRename) != 0 && watcher.treeDirs[event.Path] {
+		watcher.removeTree(event.Path)
+	}
+
+	return watcher.filtered(event)
+}
+
+// WatchFiles makes the watcher watch the given files on their own too, whether they exist or not; this never blocks,
+// so it can be called from anywhere - the function handling the changes included
+func (watcher *Watcher) WatchFiles(paths ...string) {
+	watcher.newFilesMx.Lock()
+	watcher.newFiles = append(watcher.newFiles, paths...)
+	watcher.newFilesMx.Unlock()
+
+	// waking the watching loop up, unless it's already been
+	select {
+	case watcher.newFilesIn <- struct{}{}:
+	default:
+	}
+}
+
+// returns the files to watch on their own that have been added since the last call
+func (watcher *Watcher) takeNewFiles() []string {
+	watcher.newFilesMx.Lock()
+	defer watcher.newFilesMx.Unlock()
+
+	newFiles := watcher.newFiles
+	watcher.newFiles = nil
+
+	return newFiles
+}
+
+// watches the given files on their own too, if not already done
+func (watcher *Watcher) addFiles(paths []string) {
+	for _, file := range paths {
+		file = filepath.Clean(file)
+		if watcher.files[file] {
+			continue
+		}
+		watcher.files[file] = true
+
+		// when polling, the file should not be seen as created at the next poll
+		if watcher.fsWatcher == nil {
+			if fileInfo, errStat := os.Stat(file); errStat == nil {
+				watcher.snapshot[file] = &watchedFileState{modTime: fileInfo.ModTime(), size: fileInfo.Size(), isDir: fileInfo.IsDir()}
+			}
+			continue
+		}
+
+		// else, watching its folder - which may be done already
+		Debug("Watching path: %s", filepath.Dir(file))
+		if errAdd := watcher.fsWatcher.Add(filepath.Dir(file)); errAdd != nil {
+			Warn("Could not watch '%s': %v", file, errAdd)
+		}
+	}
+}
+
+func (watcher *Watcher) filtered(event *WatchEvent) *WatchEvent {
+	// when polling, the skipped folders are looked at with each scan, so there's nothing to refresh
+	if watcher.refreshOn != nil && watcher.refreshOn(event) && watcher.fsWatcher != nil {
+		watcher.refreshTrees()
+	}
+
+	if watcher.keep != nil && !watcher.keep(event) {
+		return nil
+	}
+
+	return event
+}
+
+// watches the given folder, and its subfolders
+func (watcher *Watcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(dirPath string, entry fs.DirEntry, errWalk error) error {
+		if errWalk != nil || !entry.IsDir() {
+			return nil // the folder may have been removed in the meantime
+		}
+		if watcher.isSkipped(dirPath) {
+			return filepath.SkipDir
+		}
+		if !watcher.treeDirs[dirPath] {
+			Debug("Watching path: %s", dirPath)
+			if errAdd := watcher.fsWatcher.Add(dirPath); errAdd != nil {
+				return errAdd
+			}
+			watcher.treeDirs[dirPath] = true
+		}
+
+		return nil
+	})
+}
+
+// stops watching the folders which are now skipped, and watches the ones which are not anymore
+func (watcher *Watcher) refreshTrees() {
+	for _, dirPath := range core.GetSortedKeys(watcher.treeDirs) {
+		if watcher.treeDirs[dirPath] && watcher.isSkipped(dirPath) {
+			watcher.removeTree(dirPath)
+		}
+	}
+	for _, root := range watcher.roots {
+		if errAdd := watcher.addTree(root); errAdd != nil {
+			Warn("Could not watch '%s': %v", root, errAdd)
+		}
+	}
+}
+
+// stops watching the given folder, and its subfolders
+func (watcher *Watcher) removeTree(root string) {
+	for dirPath := range watcher.treeDirs {
+		if dirPath == root || strings.HasPrefix(dirPath, root+string(os.PathSeparator)) {
+			_ = watcher.fsWatcher.Remove(dirPath) // the watch may already be gone with the folder
+			delete(watcher.treeDirs, dirPath)
+		}
+	}
+}
+
+func (watcher *Watcher) isSkipped(dirPath string) bool {
+	return watcher.skipDir != nil && watcher.skipDir(dirPath)
+}
+
+// the folders of the files watched on their own
+func (watcher *Watcher) fileDirs() map[string]bool {
+	dirs := map[string]bool{}
+	for file := range watcher.files {
+		dirs[filepath.Dir(file)] = true
+	}
+
+	return dirs
+}
+
+func toWatchOp(fsOp fsnotify.Op) WatchOp {
+	op := WatchOp(0)
+	for _, known := range []struct {
+		fsOp fsnotify.Op
+		op   WatchOp
+	}{{fsnotify.Create, WatchCreate}, {fsnotify.Write, WatchWrite}, {fsnotify.Remove, WatchRemove}, {fsnotify.Rename, WatchRename}} {
+		if fsOp.Has(known.fsOp) {
+			op |= known.op
+		}
+	}
+
+	return op
+}
+
+// WatcherFor returns a system watcher of the given files & folders - not recursively - whose events are not batched
+//
+// Deprecated: use NewWatcher, which batches the changes, watches the folders recursively, and can poll for the changes.
 func WatcherFor(filepaths ...string) *fsnotify.Watcher {
 	// new watcher
 	watcher, errNew := fsnotify.NewWatcher()
@@ -18,3 +448,84 @@ func WatcherFor(filepaths ...string) *fsnotify.Watcher {
 
 	return watcher
 }
+
+// ----------------------------------------------------------------------------
+// Polling
+// ----------------------------------------------------------------------------
+
+// by default, how often the changes are polled for, when they are
+const defaultWatchPollInterval = time.Second
+
+var (
+	watchPolling      bool                       // if true, then the changes are always polled for
+	watchPollInterval = defaultWatchPollInterval // how often the changes are polled for, when they are
+)
+
+// SetWatchPolling forces the watchers to poll for the changes - rather than being notified by the system - if required;
+// the polling happens at the given interval, if > 0
+func SetWatchPolling(polling bool, interval time.Duration) {
+	watchPolling = polling
+	watchPollInterval = core.IfThenElse(interval > 0, interval, defaultWatchPollInterval)
+}
+
+// IsWatchPolling returns true if the changes are required to be polled for
+func IsWatchPolling() bool {
+	return watchPolling
+}
+
+// GetWatchPollInterval returns how often the changes are polled for, when they are
+func GetWatchPollInterval() time.Duration {
+	return watchPollInterval
+}
+
+// what's looked at to detect a change when polling
+type watchedFileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// returns the current state of all the watched files & folders
+func (watcher *Watcher) scan() map[string]*watchedFileState {
+	states := map[string]*watchedFileState{}
+	for file := range watcher.files {
+		if fileInfo, errStat := os.Stat(file); errStat == nil {
+			states[file] = &watchedFileState{modTime: fileInfo.ModTime(), size: fileInfo.Size(), isDir: fileInfo.IsDir()}
+		}
+	}
+	for _, root := range watcher.roots {
+		_ = filepath.WalkDir(root, func(entryPath string, entry fs.DirEntry, errWalk error) error {
+			if errWalk != nil {
+				return nil // the file may have been removed in the meantime
+			}
+			if entry.IsDir() && watcher.isSkipped(entryPath) {
+				return filepath.SkipDir
+			}
+			if fileInfo, errInfo := entry.Info(); errInfo == nil {
+				states[entryPath] = &watchedFileState{modTime: fileInfo.ModTime(), size: fileInfo.Size(), isDir: entry.IsDir()}
+			}
+			return nil
+		})
+	}
+
+	return states
+}
+
+// returns the changes between the 2 given states; the folders are only reported when created or removed
+func diffSnapshots(previous, current map[string]*watchedFileState) (events []*WatchEvent) {
+	for _, entryPath := range core.GetSortedKeys(current) {
+		state, previousState := current[entryPath], previous[entryPath]
+		if previousState == nil || previousState.isDir != state.isDir {
+			events = append(events, &WatchEvent{Path: entryPath, Op: WatchCreate})
+		} else if !state.isDir && (!state.modTime.Equal(previousState.modTime) || state.size != previousState.size) {
+			events = append(events, &WatchEvent{Path: entryPath, Op: WatchWrite})
+		}
+	}
+	for _, entryPath := range core.GetSortedKeys(previous) {
+		if current[entryPath] == nil {
+			events = append(events, &WatchEvent{Path: entryPath, Op: WatchRemove})
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,126 @@
+//go:build linux
+
+// ----------------------------------------------------------------------------
+// The code here is about detecting the filesystems whose changes are not
+// notified by the system, on Linux
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// the filesystems whose files may be changed by another machine - or by the host of a VM or a container - in which
+// case inotify does not notify the changes
+var unnotifiedFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x01021997: "9p",
+	0x786f4256: "vboxsf",
+	0x6a656a63: "virtiofs",
+}
+
+// the type of all the FUSE filesystems, most of which - being local - have their changes notified
+const fuseFilesystemTYPE = 0x65735546
+
+// the FUSE filesystems known to serve remote files, whose changes are not notified
+var unnotifiedFuseSubtypes = map[string]bool{
+	"sshfs":       true,
+	"rclone":      true,
+	"s3fs":        true,
+	"gcsfuse":     true,
+	"vmhgfs-fuse": true,
+}
+
+// returns the name of the filesystem of the given folder, if its changes may not be notified
+func unnotifiedFilesystem(dirPath string) string {
+	var stat syscall.Statfs_t
+	if errStat := syscall.Statfs(dirPath, &stat); errStat != nil {
+		return ""
+	}
+
+	if uint32(stat.Type) != fuseFilesystemTYPE {
+		return unnotifiedFilesystems[uint32(stat.Type)]
+	}
+
+	// for FUSE, it all depends on what's behind
+	mountinfo, errOpen := os.Open("/proc/self/mountinfo")
+	if errOpen != nil {
+		return ""
+	}
+	defer mountinfo.Close()
+
+	if realPath, errEval := filepath.EvalSymlinks(dirPath); errEval == nil {
+		dirPath = realPath
+	}
+	if absPath, errAbs := filepath.Abs(dirPath); errAbs == nil {
+		dirPath = absPath
+	}
+
+	fsType := mountFilesystemType(mountinfo, dirPath)
+	if subtype, isFuse := strings.CutPrefix(fsType, "fuse."); isFuse && unnotifiedFuseSubtypes[subtype] {
+		return fsType
+	}
+
+	return ""
+}
+
+// returns the type - e.g. "fuse.sshfs" - of the filesystem mounted the closest to the given absolute path, as listed in
+// the given mountinfo content, i.e. lines like: "36 35 98:0 / /mnt/remote rw,noatime master:1 - fuse.sshfs host:/ rw"
+func mountFilesystemType(mountinfo io.Reader, dirPath string) string {
+	mountPoint, fsType := "", ""
+
+	scanner := bufio.NewScanner(mountinfo)
+	for scanner.Scan() {
+		before, after, found := strings.Cut(scanner.Text(), " - ")
+		beforeFields, afterFields := strings.Fields(before), strings.Fields(after)
+		if !found || len(beforeFields) < 5 || len(afterFields) < 1 {
+			continue
+		}
+
+		// the mount point's special characters are octal-escaped, e.g. "\040" for a space
+		currentPoint := unescapeMountPoint(beforeFields[4])
+		if isPathWithin(dirPath, currentPoint) && len(currentPoint) >= len(mountPoint) {
+			mountPoint, fsType = currentPoint, afterFields[0]
+		}
+	}
+
+	return fsType
+}
+
+// returns true if the given path is the given folder, or is inside it
+func isPathWithin(path, dir string) bool {
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
+
+// replaces the octal escapes - e.g. "\040" - of a mount point, with the characters they stand for
+func unescapeMountPoint(mountPoint string) string {
+	var builder strings.Builder
+	for i := 0; i < len(mountPoint); i++ {
+		if mountPoint[i] == '\\' && i+3 < len(mountPoint) && isOctalDigits(mountPoint[i+1:i+4]) {
+			builder.WriteByte((mountPoint[i+1]-'0')<<6 | (mountPoint[i+2]-'0')<<3 | (mountPoint[i+3] - '0'))
+			i += 3
+			continue
+		}
+		builder.WriteByte(mountPoint[i])
+	}
+
+	return builder.String()
+}
+
+func isOctalDigits(digits string) bool {
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '7' {
+			return false
+		}
+	}
+
+	return true
+}
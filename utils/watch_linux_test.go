@@ -0,0 +1,34 @@
+//go:build linux
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMountFilesystemType(t *testing.T) {
+	const mountinfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+36 22 0:45 / /home/dev/remote rw,nosuid,nodev shared:20 - fuse.sshfs dev@host:/srv rw,user_id=1000
+37 22 0:46 / /home/dev/my\040drive rw,nosuid,nodev shared:21 - fuse.rclone drive: rw,user_id=1000
+38 36 0:47 / /home/dev/remote/local rw,relatime shared:22 - fuse.gocryptfs /home/dev/.vault rw
+`
+
+	tests := []struct {
+		dirPath string
+		want    string
+	}{
+		{dirPath: "/home/dev/project", want: "ext4"},
+		{dirPath: "/home/dev/remote", want: "fuse.sshfs"},
+		{dirPath: "/home/dev/remote/project", want: "fuse.sshfs"},
+		{dirPath: "/home/dev/remoteproject", want: "ext4"},
+		{dirPath: "/home/dev/my drive/project", want: "fuse.rclone"},
+		{dirPath: "/home/dev/remote/local/project", want: "fuse.gocryptfs"},
+	}
+
+	for _, test := range tests {
+		if got := mountFilesystemType(strings.NewReader(mountinfo), test.dirPath); got != test.want {
+			t.Errorf("unexpected filesystem type for '%s': got %q, want %q", test.dirPath, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+// ----------------------------------------------------------------------------
+// The code here is about detecting the filesystems whose changes are not
+// notified by the system, on the systems other than Linux
+// ----------------------------------------------------------------------------
+package utils
+
+// the filesystems are not inspected here: the polling has to be required explicitly, if needed
+func unnotifiedFilesystem(dirPath string) string {
+	return ""
+}
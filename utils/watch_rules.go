@@ -0,0 +1,282 @@
+// ----------------------------------------------------------------------------
+// The code here is about the rules telling which of the watched paths matter:
+// the include / exclude globs, and the files ignored by Git
+// ----------------------------------------------------------------------------
+package utils
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	core "github.com/aldesgroup/corego"
+)
+
+// ----------------------------------------------------------------------------
+// Globs
+// ----------------------------------------------------------------------------
+
+// a glob, like "src/**/*.go": "*" stays within a folder, "**" spans any number of folders, a glob without "/" applies
+// to the names at any depth, and a glob ending with "/" only applies to folders - like in a .gitignore file
+type glob struct {
+	segments []string
+	anyDepth bool
+	dirOnly  bool
+}
+
+func parseGlob(pattern string) *glob {
+	pattern = filepath.ToSlash(strings.TrimPrefix(pattern, "./"))
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anyDepth := !strings.Contains(pattern, "/")
+
+	return &glob{segments: strings.Split(path.Clean(strings.TrimPrefix(pattern, "/")), "/"), anyDepth: anyDepth, dirOnly: dirOnly}
+}
+
+// returns true if the given glob is well-formed
+func isValidGlob(pattern string) bool {
+	for _, segment := range parseGlob(pattern).segments {
+		if _, errMatch := path.Match(segment, ""); errMatch != nil {
+			return false
+		}
+	}
+
+	return pattern != ""
+}
+
+// returns true if the given slash-separated path matches this glob
+func (thisGlob *glob) matches(slashedPath string, isDir bool) bool {
+	if thisGlob.dirOnly && !isDir {
+		return false
+	}
+	if thisGlob.anyDepth {
+		matched, _ := path.Match(thisGlob.segments[0], path.Base(slashedPath))
+		return matched
+	}
+
+	return matchSegments(thisGlob.segments, strings.Split(slashedPath, "/"))
+}
+
+func matchSegments(patternSegments, pathSegments []string) bool {
+	for len(patternSegments) > 0 {
+		// "**" can match any number of folders
+		if patternSegments[0] == "**" {
+			for i := 0; i <= len(pathSegments); i++ {
+				if matchSegments(patternSegments[1:], pathSegments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(pathSegments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(patternSegments[0], pathSegments[0]); !matched {
+			return false
+		}
+		patternSegments, pathSegments = patternSegments[1:], pathSegments[1:]
+	}
+
+	return len(pathSegments) == 0
+}
+
+// returns the folders containing the given slash-separated path, the outermost first - not going above the project's
+// root, or above the folder a relative path outside of the project starts from
+func containingDirs(slashedPath string) []string {
+	segments := strings.Split(slashedPath, "/")
+	first := 0
+	for first < len(segments)-1 && (segments[first] == ".." || segments[first] == "") {
+		first++
+	}
+
+	dirs := []string{}
+	if first == 0 {
+		dirs = append(dirs, ".")
+	}
+	for i := first; i < len(segments)-1; i++ {
+		dirs = append(dirs, strings.Join(segments[:i+1], "/"))
+	}
+
+	return dirs
+}
+
+// ----------------------------------------------------------------------------
+// .gitignore files
+// ----------------------------------------------------------------------------
+
+// the rules read from the .gitignore files, folder by folder
+type gitIgnore struct {
+	mx         sync.Mutex
+	rulesByDir map[string][]*gitIgnoreRule
+}
+
+type gitIgnoreRule struct {
+	glob    *glob
+	negated bool
+}
+
+func newGitIgnore() *gitIgnore {
+	return &gitIgnore{rulesByDir: map[string][]*gitIgnoreRule{}}
+}
+
+// returns true if the given slash-separated path is ignored by the .gitignore files of the folders containing it
+func (thisGitIgnore *gitIgnore) ignores(slashedPath string, isDir bool) bool {
+	ignored := false
+	for _, dir := range containingDirs(slashedPath) {
+		relativePath := slashedPath
+		if dir != "." {
+			relativePath = strings.TrimPrefix(slashedPath, dir+"/")
+		}
+		// the last matching rule wins, the deepest .gitignore files being read last
+		for _, rule := range thisGitIgnore.rulesIn(dir) {
+			if rule.glob.matches(relativePath, isDir) {
+				ignored = !rule.negated
+			}
+		}
+	}
+
+	return ignored
+}
+
+// forgets the rules read from the .gitignore file in the given folder, so that they're read again when needed
+func (thisGitIgnore *gitIgnore) forget(dir string) {
+	thisGitIgnore.mx.Lock()
+	defer thisGitIgnore.mx.Unlock()
+
+	delete(thisGitIgnore.rulesByDir, dir)
+}
+
+// returns the rules of the .gitignore file in the given folder, if there's one - reading it only once, until it changes
+func (thisGitIgnore *gitIgnore) rulesIn(dir string) []*gitIgnoreRule {
+	thisGitIgnore.mx.Lock()
+	defer thisGitIgnore.mx.Unlock()
+
+	rules, alreadyRead := thisGitIgnore.rulesByDir[dir]
+	if !alreadyRead {
+		if content, errRead := os.ReadFile(filepath.Join(filepath.FromSlash(dir), ".gitignore")); errRead == nil {
+			for line := range strings.Lines(string(content)) {
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				negated := strings.HasPrefix(line, "!")
+				rules = append(rules, &gitIgnoreRule{glob: parseGlob(strings.TrimPrefix(line, "!")), negated: negated})
+			}
+		}
+		thisGitIgnore.rulesByDir[dir] = rules
+	}
+
+	return rules
+}
+
+// ----------------------------------------------------------------------------
+// Watch rules
+// ----------------------------------------------------------------------------
+
+// the rules telling which of the paths found in the watched folders matter
+type watchRules struct {
+	include      []*glob
+	exclude      []*glob
+	gitIgnore    *gitIgnore // nil if the .gitignore files are not honoured
+	descriptions []string
+}
+
+// returns the rules from the given config - if any - on top of the given default exclusions
+func newWatchRules(watchCfg *WatchConfig, defaultExcludes ...string) *watchRules {
+	if watchCfg == nil {
+		watchCfg = &WatchConfig{}
+	}
+
+	rules := &watchRules{}
+	for _, pattern := range watchCfg.Include {
+		rules.include = append(rules.include, parseGlob(pattern))
+	}
+	excludes := append(slices.Clone(defaultExcludes), watchCfg.Exclude...)
+	for _, pattern := range excludes {
+		rules.exclude = append(rules.exclude, parseGlob(pattern))
+	}
+	if !watchCfg.NoGitignore {
+		rules.gitIgnore = newGitIgnore()
+	}
+
+	// what's shown in verbose mode
+	if len(watchCfg.Include) > 0 {
+		rules.descriptions = append(rules.descriptions, "including: "+strings.Join(watchCfg.Include, ", "))
+	}
+	rules.descriptions = append(rules.descriptions, "excluding: "+strings.Join(excludes, ", "))
+	rules.descriptions = append(rules.descriptions, core.IfThenElse(rules.gitIgnore != nil, "honouring", "not honouring")+" the .gitignore files")
+
+	return rules
+}
+
+// returns true if the given folder should not be watched
+func (rules *watchRules) skipsDir(dirPath string) bool {
+	return rules.excludes(filepath.ToSlash(dirPath), true)
+}
+
+// returns true if the given change should be handed over
+func (rules *watchRules) keeps(event *WatchEvent) bool {
+	slashedPath := filepath.ToSlash(event.Path)
+	isDir := core.DirExists(event.Path)
+	if rules.excludes(slashedPath, isDir) {
+		return false
+	}
+	if len(rules.include) == 0 {
+		return true
+	}
+	if isDir {
+		return false // only the included files matter then
+	}
+
+	for _, includeGlob := range rules.include {
+		if includeGlob.matches(slashedPath, false) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns true if the given path - or one of its containing folders - is excluded, or ignored by Git
+func (rules *watchRules) excludes(slashedPath string, isDir bool) bool {
+	if slashedPath == "." {
+		return false
+	}
+
+	for _, candidate := range append(containingDirs(slashedPath), slashedPath) {
+		if candidate == "." || strings.Trim(candidate, "./") == "" {
+			continue // not going above the watched paths
+		}
+		candidateIsDir := candidate != slashedPath || isDir
+		for _, excludeGlob := range rules.exclude {
+			if excludeGlob.matches(candidate, candidateIsDir) {
+				return true
+			}
+		}
+		if rules.gitIgnore != nil && rules.gitIgnore.ignores(candidate, candidateIsDir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns true if the given change modifies these rules - i.e. it's on a .gitignore file - after taking it into account
+func (rules *watchRules) changedBy(event *WatchEvent) bool {
+	slashedPath := filepath.ToSlash(event.Path)
+	if rules.gitIgnore == nil || path.Base(slashedPath) != ".gitignore" {
+		return false
+	}
+	rules.gitIgnore.forget(path.Dir(slashedPath))
+
+	return true
+}
+
+// returns a description of these rules, for logging
+func (rules *watchRules) String() string {
+	return strings.Join(rules.descriptions, "; ")
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFilesFromOnChanges(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		t.Run(map[bool]string{false: "notified", true: "polling"}[polling], func(t *testing.T) {
+			SetWatchPolling(polling, 50*time.Millisecond)
+			t.Cleanup(func() { SetWatchPolling(false, 0) })
+
+			dir := t.TempDir()
+			mainFile, includedFile := filepath.Join(dir, "main.yaml"), filepath.Join(dir, "included.yaml")
+			writeFile(t, mainFile, "include: [included.yaml]")
+
+			// the handling of the changes makes the watcher watch a new file, like when the config includes a new one
+			changes := make(chan []*WatchEvent, 10)
+			var watcher *Watcher
+			watcher = NewWatcher("testing", func(events []*WatchEvent) {
+				watcher.WatchFiles(includedFile)
+				changes <- events
+			}, mainFile).WithQuietPeriod(20 * time.Millisecond)
+
+			ctx := newBaseCancelableContext()
+			t.Cleanup(ctx.CancelAll)
+			watcher.Start(ctx)
+
+			// the new file should be watched once the 1st change has been handled
+			writeFile(t, mainFile, "include: [included.yaml]\nappname: test")
+			waitForChange(t, changes, mainFile)
+			time.Sleep(100 * time.Millisecond) // the polling should see the file as already there
+			writeFile(t, includedFile, "appdesc: test")
+			waitForChange(t, changes, includedFile)
+		})
+	}
+}
+
+func writeFile(t *testing.T, filePath, content string) {
+	t.Helper()
+
+	if errWrite := os.WriteFile(filePath, []byte(content), 0o644); errWrite != nil {
+		t.Fatal(errWrite)
+	}
+}
+
+// waits for a batch of changes including the given file
+func waitForChange(t *testing.T, changes <-chan []*WatchEvent, changedFile string) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case events := <-changes:
+			for _, event := range events {
+				if event.Path == changedFile {
+					return
+				}
+			}
+		case <-timeout:
+			t.Fatalf("no change seen on '%s'", changedFile)
+		}
+	}
+}